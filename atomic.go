@@ -26,3 +26,33 @@ func (l *logger) atomicSetTCache(tc *targetCache) {
 	ptr := (*unsafe.Pointer)(unsafe.Pointer(&l.tcache))
 	atomic.StorePointer(ptr, unsafe.Pointer(tc))
 }
+
+func (lc *levelCache) atomicGetICache() map[uintptr]callSite {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(&lc.iCache))
+	return *(*map[uintptr]callSite)(atomic.LoadPointer(ptr))
+}
+
+func (lc *levelCache) atomicSetICache(iCache *map[uintptr]callSite) {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(&lc.iCache))
+	atomic.StorePointer(ptr, unsafe.Pointer(iCache))
+}
+
+func atomicGetLevels() *levelRegistry {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(&levels))
+	return (*levelRegistry)(atomic.LoadPointer(ptr))
+}
+
+func atomicSetLevels(reg *levelRegistry) {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(&levels))
+	atomic.StorePointer(ptr, unsafe.Pointer(reg))
+}
+
+func atomicGetSystemFields() map[string]bool {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(&systemFields))
+	return *(*map[string]bool)(atomic.LoadPointer(ptr))
+}
+
+func atomicSetSystemFields(fields *map[string]bool) {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(&systemFields))
+	atomic.StorePointer(ptr, unsafe.Pointer(fields))
+}
@@ -0,0 +1,119 @@
+package slog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A deduper collapses identical lines logged from the same call site within a
+// window of each other. The first such line is logged as usual, and the rest are
+// counted and summarized in a single line once the window has elapsed.
+type deduper struct {
+	window time.Duration
+
+	lock sync.Mutex
+	seen map[dedupID]*duplicate
+}
+
+// Lines are only duplicates of lines from the same Logger: a deduper is shared
+// by a Logger's children, and their lines might be bound for different targets.
+type dedupID struct {
+	logger *logger
+	key    string
+}
+
+type duplicate struct {
+	logger *logger
+	level  Level
+	line   map[string]interface{}
+	count  int
+	first  time.Time
+	last   time.Time
+}
+
+func newDeduper(window time.Duration) *deduper {
+	return &deduper{
+		window: window,
+		seen:   make(map[dedupID]*duplicate),
+	}
+}
+
+// Report whether the given record, logged by l from the call site pc, should be
+// dispatched. If it shouldn't, it has been folded into the summary for an
+// earlier identical line.
+func (d *deduper) admit(l *logger, pc uintptr, r *record) bool {
+	line := r.asMap()
+	id := dedupID{l, dedupKey(r.level, pc, line)}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if dup, ok := d.seen[id]; ok {
+		dup.count++
+		dup.last = r.time
+		return false
+	}
+
+	cp := make(map[string]interface{}, len(line)+3)
+	for k, v := range line {
+		cp[k] = v
+	}
+	d.seen[id] = &duplicate{
+		logger: l,
+		level:  r.level,
+		line:   cp,
+		first:  r.time,
+	}
+	time.AfterFunc(d.window, func() {
+		d.flush(id)
+	})
+	return true
+}
+
+func (d *deduper) flush(id dedupID) {
+	d.lock.Lock()
+	dup := d.seen[id]
+	delete(d.seen, id)
+	d.lock.Unlock()
+
+	if dup.count == 0 {
+		return
+	}
+	dup.line["$repeated"] = dup.count
+	dup.line["$first"] = dup.first
+	dup.line["$last"] = dup.last
+	// The summary is a line of its own, so it gets a number of its own.
+	if seq := dup.logger.getLCache().sequence; seq != nil {
+		dup.line["$seq"] = seq.next()
+	}
+	r := mapRecord(dup.level, dup.line)
+	dup.logger.getTCache().dispatch(r)
+	r.release()
+}
+
+// System fields that differ from line to line, and which would otherwise make
+// every line unique.
+var volatileFields = map[string]bool{
+	"$time":        true,
+	"$seq":         true,
+	"$sampled_out": true,
+}
+
+// Lines are identical if they share a level, a call site, and all of their
+// fields, except for volatile system fields like "$time". Other system fields,
+// like "$msg", are part of what makes a line what it is.
+func dedupKey(level Level, pc uintptr, line map[string]interface{}) string {
+	fields := make(map[string]interface{}, len(line))
+	for k, v := range line {
+		if !volatileFields[k] {
+			fields[k] = v
+		}
+	}
+	// Format would cut long values short, which could make lines that
+	// differ only near their ends look identical.
+	r := mapRecord(0, fields)
+	key := appendFormat([]byte(fmt.Sprintf("%d %x ", level, pc)), r,
+		limiter{})
+	r.release()
+	return string(key)
+}
@@ -0,0 +1,174 @@
+package slog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupKey(t *testing.T) {
+	t.Parallel()
+
+	line := Data{"$level": LError, "$time": "now", "err": "oops"}
+	same := Data{"$level": LError, "$time": "later", "err": "oops"}
+	different := Data{"$level": LError, "$time": "now", "err": "oh no"}
+
+	if dedupKey(LError, 1, line) != dedupKey(LError, 1, same) {
+		t.Error("Expected lines differing only in $time to be identical")
+	}
+	if dedupKey(LError, 1, line) == dedupKey(LError, 1, different) {
+		t.Error("Expected lines with different fields to differ")
+	}
+	if dedupKey(LError, 1, line) == dedupKey(LError, 2, line) {
+		t.Error("Expected lines from different call sites to differ")
+	}
+	msg := Data{"$level": LError, "$time": "now", "$msg": "request 1"}
+	msg2 := Data{"$level": LError, "$seq": 2, "$msg": "request 2"}
+	if dedupKey(LError, 1, msg) == dedupKey(LError, 1, msg2) {
+		t.Error("Expected lines with different messages to differ")
+	}
+	if dedupKey(LError, 1, line) == dedupKey(LWarn, 1, line) {
+		t.Error("Expected lines at different levels to differ")
+	}
+}
+
+func TestDedup(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan map[string]interface{}, 10)
+	root.SlogTo(target)
+	sub := root.Bind(Data{"sub": 1})
+	sub.SetDedup(20 * time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		sub.Error(Data{"err": "oops"})
+		root.Error(Data{"err": "oops"})
+	}
+	sub.Error(Data{"err": "oh no"})
+
+	if line := <-target; line["sub"] != 1 || line["$repeated"] != nil {
+		t.Errorf("Expected first line, got %v", line)
+	}
+	for i := 0; i < 5; i++ {
+		if line := <-target; line["sub"] != nil {
+			t.Errorf("Expected line from root, got %v", line)
+		}
+	}
+	if line := <-target; line["err"] != "oh no" {
+		t.Errorf("Expected different line, got %v", line)
+	}
+
+	summary := <-target
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("Expected summary to be sent after the window elapsed")
+	}
+	if summary["err"] != "oops" || summary["sub"] != 1 ||
+		summary["$level"] != LError || summary["$repeated"] != 4 {
+		t.Errorf("Unexpected summary %v", summary)
+	}
+	if summary["$first"] != fakeClock() || summary["$last"] != fakeClock() {
+		t.Errorf("Bad summary times %v and %v", summary["$first"],
+			summary["$last"])
+	}
+
+	// The second line of the "oh no" run isn't a duplicate, since it's
+	// from a different call site.
+	sub.Error(Data{"err": "oh no"})
+	if line := <-target; line["err"] != "oh no" || line["$repeated"] != nil {
+		t.Errorf("Unexpected line %v", line)
+	}
+
+	sub.SetDedup(0)
+	sub.Error(Data{"err": "oops"})
+	sub.Error(Data{"err": "oops"})
+	for i := 0; i < 2; i++ {
+		if line := <-target; line["err"] != "oops" {
+			t.Errorf("Unexpected line %v", line)
+		}
+	}
+}
+
+func TestDedupLogf(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	root.LogTo(target)
+	root.SetDedup(time.Hour)
+
+	for i := 0; i < 4; i++ {
+		root.Errorf("request %d failed", i%3)
+	}
+
+	expectLines(t, target, []string{
+		`$level="ERROR" $time="` + now + `" $msg="request 0 failed"` + "\n",
+		`$level="ERROR" $time="` + now + `" $msg="request 1 failed"` + "\n",
+		`$level="ERROR" $time="` + now + `" $msg="request 2 failed"` + "\n",
+	})
+	select {
+	case line := <-target:
+		t.Errorf("Expected the repeated message to be held back, got %q",
+			line)
+	default:
+	}
+}
+
+func TestDedupSequence(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan map[string]interface{}, 10)
+	root.SlogTo(target)
+	root.SetDedup(20 * time.Millisecond)
+	root.SetSequence(LoggerSequence)
+
+	for i := 0; i < 4; i++ {
+		root.Error(Data{"err": "oops"})
+	}
+	root.Error(Data{"err": "oh no"})
+
+	for _, seq := range []uint64{1, 2} {
+		if line := <-target; line["$seq"] != seq {
+			t.Errorf("Expected $seq %d, got %v", seq, line)
+		}
+	}
+	summary := <-target
+	if summary["$repeated"] != 3 || summary["$seq"] != uint64(3) {
+		t.Errorf("Expected summary with $seq 3, got %v", summary)
+	}
+}
+
+func TestDedupSiblings(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	root.SetDedup(20 * time.Millisecond)
+	a, b := root.Bind(nil), root.Bind(nil)
+	aT := make(chan map[string]interface{}, 10)
+	bT := make(chan map[string]interface{}, 10)
+	a.SlogTo(aT)
+	b.SlogTo(bT)
+
+	logOops := func(l Logger) {
+		l.Error(Data{"err": "oops"})
+	}
+	for _, l := range []Logger{a, b, b} {
+		logOops(l)
+	}
+
+	if line := <-aT; line["$repeated"] != nil {
+		t.Errorf("Expected first line, got %v", line)
+	}
+	if line := <-bT; line["$repeated"] != nil {
+		t.Errorf("Expected first line, got %v", line)
+	}
+	if line := <-bT; line["$repeated"] != 1 {
+		t.Errorf("Expected summary, got %v", line)
+	}
+	select {
+	case line := <-aT:
+		t.Errorf("Unexpected line %v", line)
+	default:
+	}
+}
@@ -1,57 +1,27 @@
 package slog
 
-import (
-	"fmt"
-	"time"
-)
-
-var root logger
-
-func makeRoot(now fmt.Stringer) logger {
-	root := logger{
-		defaultTarget: stdout,
-		context: map[string]interface{}{
-			"$time": now,
-		},
+import "time"
+
+var root *logger
+
+func makeRoot(clock func() time.Time) *logger {
+	root := &logger{
+		clock:         clock,
+		defaultTarget: &target{fn: stdout},
 	}
 	root.genLCache(nil)
 	root.genTCache(nil)
 	return root
 }
 
-type currentTime struct{}
-
-func (_ currentTime) String() string {
-	// TODO(carl): Just hardcode this. It'll be faster and we can even make
-	// it fixed-width.
-	return time.Now().UTC().Format(time.RFC3339Nano)
-}
-
 func init() {
-	root = makeRoot(currentTime{})
+	root = makeRoot(func() time.Time {
+		return Clock()
+	})
 }
 
 var DefaultLevel = LInfo
 
-// String implements the fmt.Stringer interface by returning one of DEBUG, INFO,
-// WARN, or ERROR.
-func (l Level) String() string {
-	switch l {
-	case LDebug:
-		return "DEBUG"
-	case LInfo:
-		return "INFO"
-	case LWarn:
-		return "WARN"
-	case LError:
-		return "ERROR"
-	default:
-		// Unclear how this would happen, but it's probably not nice to
-		// panic().
-		return fmt.Sprintf("Level(%d)", l)
-	}
-}
-
 // Bind returns a new Logger forked from the global root logger that
 // additionally binds the given context variables. It is the only way to create
 // new Loggers, therefore all Loggers, regardless of where they are created have
@@ -60,22 +30,91 @@ func Bind(context map[string]interface{}) Logger {
 	return root.Bind(context)
 }
 
+// BindGroup returns a new Logger forked from the global root logger that
+// additionally binds the given context variables within the named group. See
+// Logger.BindGroup for details.
+func BindGroup(name string, context map[string]interface{}) Logger {
+	return root.BindGroup(name, context)
+}
+
 // SetLevel sets the log level for a given selector on the root logger. See the
 // documentation for Logger.SetLevel for the syntax accepted for the selector.
 func SetLevel(selector string, level Level) {
 	root.SetLevel(selector, level)
 }
 
+// SetSampling sets the sampling policy for a given selector on the root logger.
+// See the documentation for Logger.SetSampling for details.
+func SetSampling(selector string, sampling Sampling) {
+	root.SetSampling(selector, sampling)
+}
+
+// SetDedup sets the deduplication window of the root logger. See the
+// documentation for Logger.SetDedup for details.
+func SetDedup(window time.Duration) {
+	root.SetDedup(window)
+}
+
+// SetSequence controls the numbering of lines logged through the root logger.
+// See the documentation for Logger.SetSequence for details.
+func SetSequence(scope SequenceScope) {
+	root.SetSequence(scope)
+}
+
+// SetSnapshot controls how the values of lines logged through the root logger
+// are captured. See the documentation for Logger.SetSnapshot for details.
+func SetSnapshot(mode SnapshotMode) {
+	root.SetSnapshot(mode)
+}
+
+// Redact adds a redaction policy to the root logger. See the documentation for
+// Logger.Redact for details.
+func Redact(policy Redaction) {
+	root.Redact(policy)
+}
+
+// AddHook adds a Hook to the root logger, which runs on every line. The
+// returned function removes it. See Logger.AddHook for details.
+func AddHook(hook Hook) func() {
+	return root.AddHook(hook)
+}
+
 // LogTo logs pre-formatted log lines at the given levels to a channel. If you
 // do not pass any levels, the channel will be used as the default logger for
-// levels not otherwise configured.
-func LogTo(target chan<- string, levels ...Level) {
-	root.LogTo(target, levels...)
+// levels not otherwise configured. The returned function detaches the channel;
+// see Logger.LogTo for details.
+func LogTo(target chan<- string, levels ...Level) func() {
+	return root.LogTo(target, levels...)
 }
 
 // SlogTo logs raw log lines at the given levels to a channel. If you do not
 // pass any levels, the channel will be used as the default logger for levels
-// not otherwise configured.
-func SlogTo(target chan<- map[string]interface{}, levels ...Level) {
-	root.SlogTo(target, levels...)
+// not otherwise configured. The returned function detaches the channel; see
+// Logger.LogTo for details.
+func SlogTo(target chan<- map[string]interface{}, levels ...Level) func() {
+	return root.SlogTo(target, levels...)
+}
+
+// LogAtLeast logs pre-formatted log lines at or above the given level to a
+// channel. See Logger.LogAtLeast for details.
+func LogAtLeast(target chan<- string, level Level) func() {
+	return root.LogAtLeast(target, level)
+}
+
+// SlogAtLeast logs raw log lines at or above the given level to a channel. See
+// Logger.LogAtLeast for details.
+func SlogAtLeast(target chan<- map[string]interface{}, level Level) func() {
+	return root.SlogAtLeast(target, level)
+}
+
+// LogWhen logs pre-formatted log lines that match the given Predicate to a
+// channel. See Logger.LogWhen for details.
+func LogWhen(target chan<- string, pred Predicate) func() {
+	return root.LogWhen(target, pred)
+}
+
+// SlogWhen logs raw log lines that match the given Predicate to a channel. See
+// Logger.LogWhen for details.
+func SlogWhen(target chan<- map[string]interface{}, pred Predicate) func() {
+	return root.SlogWhen(target, pred)
 }
@@ -0,0 +1,197 @@
+package slog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// The most errors we're willing to visit when unwrapping an error, in case
+// someone has managed to construct a cycle.
+const maxErrorChain = 32
+
+// The details of an error, as rendered by the formatters.
+type errorDetail struct {
+	// The error's message.
+	msg string
+	// The messages of the errors it wraps, in depth-first order.
+	chain []string
+	// Fields provided by errors that implement LogFields, with outer errors
+	// taking precedence. Their values are resolved as with resolveValue.
+	fields Data
+	// The stack trace of the innermost error that has one.
+	stack string
+}
+
+func describeError(err error) (d errorDetail) {
+	if re, ok := err.(*redactedError); ok {
+		return re.detail
+	}
+	d.msg = fmt.Sprint(err)
+
+	var pcs []uintptr
+	n := 0
+	var walk func(e error, depth int)
+	walk = func(e error, depth int) {
+		if e == nil || n >= maxErrorChain {
+			return
+		}
+		n++
+		if depth > 0 {
+			d.chain = append(d.chain, fmt.Sprint(e))
+		}
+		if lf, ok := e.(interface {
+			LogFields() map[string]interface{}
+		}); ok {
+			for k, v := range safeLogFields(lf) {
+				if _, ok := d.fields[k]; ok {
+					continue
+				}
+				if d.fields == nil {
+					d.fields = make(Data)
+				}
+				d.fields[k] = resolveValue(v, 0)
+			}
+		}
+		if st := errorStack(e); st != nil {
+			pcs = st
+		}
+
+		for _, inner := range safeUnwrap(e) {
+			walk(inner, depth+1)
+		}
+	}
+	walk(err, 0)
+
+	if pcs != nil {
+		d.stack = formatStack(pcs)
+	}
+	return d
+}
+
+// Return the errors that e wraps. Like fmt, we don't let errors with nil
+// receivers take down the caller.
+func safeUnwrap(e error) (errs []error) {
+	defer func() {
+		if recover() != nil {
+			errs = nil
+		}
+	}()
+	if multi, ok := e.(interface{ Unwrap() []error }); ok {
+		return multi.Unwrap()
+	}
+	if inner := errors.Unwrap(e); inner != nil {
+		return []error{inner}
+	}
+	return nil
+}
+
+func safeLogFields(lf interface {
+	LogFields() map[string]interface{}
+}) (fields map[string]interface{}) {
+	defer func() {
+		if recover() != nil {
+			fields = nil
+		}
+	}()
+	return lf.LogFields()
+}
+
+// Return the stack trace carried by an error, if it has one. We accept any
+// StackTrace method that returns a slice of program counters, which includes
+// the one provided by github.com/pkg/errors, without having to depend on it.
+func errorStack(err error) (pcs []uintptr) {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() {
+		return nil
+	}
+	t := m.Type()
+	if t.NumIn() != 0 || t.NumOut() != 1 ||
+		t.Out(0).Kind() != reflect.Slice ||
+		t.Out(0).Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	// Errors with nil receivers might not be too happy about this.
+	defer func() {
+		if recover() != nil {
+			pcs = nil
+		}
+	}()
+	st := m.Call(nil)[0]
+	pcs = make([]uintptr, st.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(st.Index(i).Uint())
+	}
+	return pcs
+}
+
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			if sb.Len() > 0 {
+				sb.WriteByte('\n')
+			}
+			sb.WriteString(frame.Function)
+			sb.WriteString("\n\t")
+			sb.WriteString(frame.File)
+			sb.WriteByte(':')
+			sb.WriteString(strconv.Itoa(frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// Append an error as a series of key-value pairs, as with Format.
+func appendError(buf []byte, lim *limiter, key string, err error) []byte {
+	d := describeError(err)
+	buf = appendKey(buf, lim, key)
+	buf = appendValue(buf, lim, d.msg)
+	for i, msg := range d.chain {
+		buf = appendKey(buf, lim, key+".chain."+strconv.Itoa(i))
+		buf = appendValue(buf, lim, msg)
+	}
+	for _, k := range sortedKeys(d.fields) {
+		buf = appendPair(buf, lim, key+"."+k, d.fields[k])
+	}
+	if d.stack != "" {
+		buf = appendKey(buf, lim, key+".stack")
+		buf = appendValue(buf, lim, d.stack)
+	}
+	return buf
+}
+
+// Append an error as a series of JSON object members, as with FormatJSON.
+func appendJSONError(buf []byte, lim *limiter, key string, err error) []byte {
+	d := describeError(err)
+	buf = appendJSONKey(buf, lim, key)
+	buf = appendJSONValueString(buf, lim, d.msg)
+	if len(d.chain) > 0 {
+		buf = appendJSONKey(buf, lim, key+".chain")
+		buf = append(buf, '[')
+		for i, msg := range d.chain {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONValueString(buf, lim, msg)
+		}
+		buf = append(buf, ']')
+	}
+	for _, k := range sortedKeys(d.fields) {
+		buf = appendJSONMember(buf, lim, key+"."+k, d.fields[k])
+	}
+	if d.stack != "" {
+		buf = appendJSONKey(buf, lim, key+".stack")
+		buf = appendJSONValueString(buf, lim, d.stack)
+	}
+	return buf
+}
@@ -0,0 +1,117 @@
+package slog
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d", e.status)
+}
+
+func (e *statusError) LogFields() map[string]interface{} {
+	return map[string]interface{}{"status": e.status, "retry": false}
+}
+
+type wrapErr struct {
+	inner error
+}
+
+func (e *wrapErr) Error() string {
+	return "wrapped: " + e.inner.Error()
+}
+
+func (e *wrapErr) Unwrap() error {
+	return e.inner
+}
+
+// Like the frames of github.com/pkg/errors.
+type frame uintptr
+
+type stackError struct {
+	stack []frame
+}
+
+func (e *stackError) Error() string {
+	return "with stack"
+}
+
+func (e *stackError) StackTrace() []frame {
+	return e.stack
+}
+
+func newStackError() error {
+	pcs := make([]uintptr, 1)
+	runtime.Callers(1, pcs)
+	return &stackError{[]frame{frame(pcs[0])}}
+}
+
+var errorTests = []struct {
+	err  error
+	out  string
+	json string
+}{
+	{
+		errors.New("oops"),
+		`err="oops"`,
+		`{"err":"oops"}`,
+	},
+	{
+		fmt.Errorf("outer: %w", fmt.Errorf("middle: %w",
+			errors.New("inner"))),
+		`err="outer: middle: inner" err.chain.0="middle: inner" err.chain.1="inner"`,
+		`{"err":"outer: middle: inner","err.chain":["middle: inner","inner"]}`,
+	},
+	{
+		errors.Join(errors.New("a"), fmt.Errorf("b: %w",
+			&statusError{404})),
+		`err="a\nb: status 404" err.chain.0="a" err.chain.1="b: status 404" err.chain.2="status 404" err.retry="false" err.status="404"`,
+		`{"err":"a\nb: status 404","err.chain":["a","b: status 404","status 404"],"err.retry":false,"err.status":404}`,
+	},
+	{
+		(*statusError)(nil),
+		`err="<nil>"`,
+		`{"err":"<nil>"}`,
+	},
+	{
+		(*wrapErr)(nil),
+		`err="<nil>"`,
+		`{"err":"<nil>"}`,
+	},
+}
+
+func TestFormatErrors(t *testing.T) {
+	t.Parallel()
+	for _, test := range errorTests {
+		line := map[string]interface{}{"err": test.err}
+		if out := Format(line); out != test.out+"\n" {
+			t.Errorf("Expected Format(%v) = %q, got %q", line,
+				test.out, out)
+		}
+		if out := FormatJSON(line); out != test.json+"\n" {
+			t.Errorf("Expected FormatJSON(%v) = %q, got %q", line,
+				test.json, out)
+		}
+	}
+}
+
+func TestFormatErrorStack(t *testing.T) {
+	t.Parallel()
+	err := fmt.Errorf("wrapped: %w", newStackError())
+	out := Format(Data{"e": Data{"err": err}})
+	prefix := `e.err="wrapped: with stack" e.err.chain.0="with stack" ` +
+		`e.err.stack="github.com/zenazn/slog.newStackError\n\t`
+	if !strings.HasPrefix(out, prefix) {
+		t.Errorf("Expected %q to start with %q", out, prefix)
+	}
+	if !strings.Contains(out, `errors_test.go:`) {
+		t.Errorf("Expected %q to contain a file name", out)
+	}
+}
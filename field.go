@@ -0,0 +1,141 @@
+package slog
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+/*
+A Field is a single strongly-typed key-value pair, for use with methods like
+Logger.LogFields. Unlike Data, Fields do not require building a map, and the
+values of most types of Field can be formatted without allocating. Fields are
+constructed with functions like String and Int.
+*/
+type Field struct {
+	Key string
+
+	kind  fieldKind
+	num   int64
+	str   string
+	iface interface{}
+}
+
+type fieldKind int
+
+const (
+	anyKind fieldKind = iota
+	stringKind
+	intKind
+	int64Kind
+	float64Kind
+	boolKind
+	durationKind
+)
+
+// String constructs a Field with a string value.
+func String(key, value string) Field {
+	return Field{Key: key, kind: stringKind, str: value}
+}
+
+// Int constructs a Field with an int value.
+func Int(key string, value int) Field {
+	return Field{Key: key, kind: intKind, num: int64(value)}
+}
+
+// Int64 constructs a Field with an int64 value.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, kind: int64Kind, num: value}
+}
+
+// Float64 constructs a Field with a float64 value.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, kind: float64Kind,
+		num: int64(math.Float64bits(value))}
+}
+
+// Bool constructs a Field with a bool value.
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, kind: boolKind}
+	if value {
+		f.num = 1
+	}
+	return f
+}
+
+// Dur constructs a Field with a time.Duration value.
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, kind: durationKind, num: int64(value)}
+}
+
+// Time constructs a Field with a time.Time value.
+func Time(key string, value time.Time) Field {
+	return Any(key, value)
+}
+
+// Err constructs a Field with the key "err" and the given error as its value.
+func Err(err error) Field {
+	return Any("err", err)
+}
+
+// Any constructs a Field with an arbitrary value, which is treated just like
+// values in Data.
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, kind: anyKind, iface: value}
+}
+
+// Group constructs a Field whose value is a group of fields, which is
+// equivalent to using a Data value. See Data for details.
+func Group(key string, data map[string]interface{}) Field {
+	return Any(key, Data(data))
+}
+
+// Value returns the Field's value.
+func (f Field) Value() interface{} {
+	switch f.kind {
+	case stringKind:
+		return f.str
+	case intKind:
+		return int(f.num)
+	case int64Kind:
+		return f.num
+	case float64Kind:
+		return math.Float64frombits(uint64(f.num))
+	case boolKind:
+		return f.num != 0
+	case durationKind:
+		return time.Duration(f.num)
+	default:
+		return f.iface
+	}
+}
+
+// Append the quoted form of the Field's value, just like appendValue would do for
+// f.Value(), but without boxing it.
+func (f Field) appendValue(buf []byte, lim *limiter) []byte {
+	switch f.kind {
+	case stringKind:
+		return appendString(buf, lim, f.str)
+	case intKind, int64Kind:
+		buf = append(buf, '"')
+		buf = strconv.AppendInt(buf, f.num, 10)
+	case float64Kind:
+		buf = append(buf, '"')
+		buf = strconv.AppendFloat(buf,
+			math.Float64frombits(uint64(f.num)), 'g', -1, 64)
+	case boolKind:
+		buf = append(buf, '"')
+		buf = strconv.AppendBool(buf, f.num != 0)
+	case durationKind:
+		return strconv.AppendQuote(buf, time.Duration(f.num).String())
+	default:
+		return appendValue(buf, lim, f.iface)
+	}
+	return append(buf, '"')
+}
+
+// PreserveFieldOrder controls the order in which Format lays out lines logged
+// with Fields. By default, all keys are sorted, but if PreserveFieldOrder is
+// true, the Fields are instead printed in the order they were passed, after the
+// system fields and bound context (which are sorted as usual).
+var PreserveFieldOrder = false
@@ -0,0 +1,49 @@
+package slog
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var fieldTests = []struct {
+	field Field
+	value interface{}
+}{
+	{String("k", "hello world"), "hello world"},
+	{String("k", `"`), `"`},
+	{Int("k", -12345), -12345},
+	{Int64("k", math.MaxInt64), int64(math.MaxInt64)},
+	{Float64("k", 1.5), 1.5},
+	{Float64("k", math.Inf(-1)), math.Inf(-1)},
+	{Bool("k", true), true},
+	{Bool("k", false), false},
+	{Dur("k", 1500*time.Millisecond), 1500 * time.Millisecond},
+	{Time("k", fakeClock()), fakeClock()},
+	{Err(errors.New("oops")), errors.New("oops")},
+	{Any("k", foo{1, 2}), foo{1, 2}},
+	{Any("k", nil), nil},
+}
+
+func TestFields(t *testing.T) {
+	t.Parallel()
+	for _, test := range fieldTests {
+		v := test.field.Value()
+		if !reflect.DeepEqual(v, test.value) {
+			t.Errorf("Expected value %#v, got %#v", test.value, v)
+		}
+		// Formatting a Field should be no different than formatting
+		// its value.
+		expected := string(appendValue(nil, &limiter{}, v))
+		actual := string(test.field.appendValue(nil, &limiter{}))
+		if expected != actual {
+			t.Errorf("Expected %v to format as %s, got %s", v,
+				expected, actual)
+		}
+	}
+	if key := Err(nil).Key; key != "err" {
+		t.Errorf("Expected Err to use the key err, got %q", key)
+	}
+}
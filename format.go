@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -14,25 +15,158 @@ func needsQuote(r rune) bool {
 }
 
 // Format formats a generic map into string form. It sorts all key-value pairs
-// by increasing key, and prints strings of the form "key1=value1 key2=value2"
-// etc. The values are first formatted using package fmt's "%+v" encoding, then
-// quoted using double quotes. The keys are quoted if they'd be ambiguous.
+// by increasing key, except that the message "$msg" is placed immediately after
+// the other system fields (those whose keys begin with "$"), and prints strings
+// of the form "key1=value1 key2=value2" etc. The values are first formatted
+// using package fmt's "%+v" encoding (except for time.Time values, which are
+// formatted with DefaultTimeFormat), then quoted using double quotes. The keys
+// are quoted if they'd be ambiguous. Groups (see Data) are flattened into one
+// key-value pair per field, with the group's key and the field's key joined by
+// a period. Empty groups are omitted.
+//
+// Errors are rendered as the result of their Error method. If an error wraps
+// other errors (see errors.Unwrap and errors.Join), their messages are
+// additionally rendered as "key.chain.0", "key.chain.1", and so on. Errors in
+// the chain that implement
+//
+//	LogFields() map[string]interface{}
+//
+// contribute their fields as "key.field", and if an error in the chain has a
+// StackTrace method that returns a slice of program counters (like those in
+// github.com/pkg/errors do), the innermost such trace is rendered as
+// "key.stack".
+//
+// Lines are subject to the limits MaxValueLength, MaxFields, and MaxRecordSize.
+// Lines with omitted fields get an "$omitted" field with the number of fields
+// that were omitted.
 func Format(data map[string]interface{}) string {
-	keys := make([]string, 0, len(data))
-	for k := range data {
+	r := mapRecord(0, data)
+	s := r.format()
+	r.release()
+	return s
+}
+
+// Format a record, enforcing the given limits. The limiter's start is set to
+// where the record begins.
+func appendFormat(buf []byte, r *record, lim limiter) []byte {
+	lim.start = len(buf)
+	for _, k := range r.orderedKeys() {
+		mark := len(buf)
+		buf = r.appendField(buf, &lim, k)
+		buf = lim.check(buf, mark, k)
+	}
+	if lim.omitted > 0 {
+		buf = appendPair(buf, &lim, omittedField, lim.omitted)
+	}
+	return append(buf, '\n')
+}
+
+// Append a key and its value, expanding groups into one pair per field and
+// errors into one pair per detail.
+func appendPair(buf []byte, lim *limiter, key string, v interface{}) []byte {
+	switch v := v.(type) {
+	case Data:
+		for _, k := range sortedKeys(v) {
+			buf = appendPair(buf, lim, key+"."+k, v[k])
+		}
+		return buf
+	case error:
+		return appendError(buf, lim, key, v)
+	}
+	buf = appendKey(buf, lim, key)
+	return appendValue(buf, lim, v)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
+	return keys
+}
 
-	for i, k := range keys {
-		val := fmt.Sprintf("%+v", data[k])
+func appendKey(buf []byte, lim *limiter, key string) []byte {
+	if len(buf) > lim.start {
+		buf = append(buf, ' ')
+	}
+	if strings.IndexFunc(key, needsQuote) >= 0 || key == "" {
+		buf = strconv.AppendQuote(buf, key)
+	} else {
+		buf = append(buf, key...)
+	}
+	return append(buf, '=')
+}
 
-		if strings.IndexFunc(k, needsQuote) >= 0 || k == "" {
-			k = strconv.Quote(k)
-		}
+// Append the quoted form of a value. This is equivalent to quoting the result of
+// formatting it with "%+v", but common types are special-cased so that they can
+// be formatted without allocating.
+func appendValue(buf []byte, lim *limiter, v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendString(buf, lim, v)
+	case Level:
+		return strconv.AppendQuote(buf, v.String())
+	case time.Time:
+		return appendTime(buf, v)
+	case bool:
+		buf = append(buf, '"')
+		buf = strconv.AppendBool(buf, v)
+	case int:
+		buf = append(buf, '"')
+		buf = strconv.AppendInt(buf, int64(v), 10)
+	case int8:
+		buf = append(buf, '"')
+		buf = strconv.AppendInt(buf, int64(v), 10)
+	case int16:
+		buf = append(buf, '"')
+		buf = strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		buf = append(buf, '"')
+		buf = strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		buf = append(buf, '"')
+		buf = strconv.AppendInt(buf, v, 10)
+	case uint:
+		buf = append(buf, '"')
+		buf = strconv.AppendUint(buf, uint64(v), 10)
+	case uint8:
+		buf = append(buf, '"')
+		buf = strconv.AppendUint(buf, uint64(v), 10)
+	case uint16:
+		buf = append(buf, '"')
+		buf = strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		buf = append(buf, '"')
+		buf = strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		buf = append(buf, '"')
+		buf = strconv.AppendUint(buf, v, 10)
+	case float32:
+		buf = append(buf, '"')
+		buf = strconv.AppendFloat(buf, float64(v), 'g', -1, 32)
+	case float64:
+		buf = append(buf, '"')
+		buf = strconv.AppendFloat(buf, v, 'g', -1, 64)
+	default:
+		return appendString(buf, lim, fmt.Sprintf("%+v", v))
+	}
+	return append(buf, '"')
+}
 
-		// Reuse storage because I'm a terrible person.
-		keys[i] = k + "=" + strconv.Quote(val)
+// Append a quoted string, truncating it if it's too long.
+func appendString(buf []byte, lim *limiter, s string) []byte {
+	s, cut := lim.truncate(s)
+	buf = strconv.AppendQuote(buf, s)
+	if cut > 0 {
+		buf = appendTruncated(buf[:len(buf)-1], cut)
+		buf = append(buf, '"')
 	}
-	return strings.Join(keys, " ") + "\n"
+	return buf
+}
+
+func appendTime(buf []byte, t time.Time) []byte {
+	buf = append(buf, '"')
+	buf = DefaultTimeFormat.AppendFormat(buf, t)
+	return append(buf, '"')
 }
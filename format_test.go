@@ -38,6 +38,23 @@ var formatTests = []struct {
 		map[string]interface{}{"": "hi"},
 		`""="hi"`,
 	},
+	{
+		map[string]interface{}{"$msg": "hi", "$a": 1, "$z": 2, "a": 3},
+		`$a="1" $z="2" $msg="hi" a="3"`,
+	},
+	{
+		map[string]interface{}{
+			"a":    Data{},
+			"http": Data{"method": "GET", "a b": Data{"c": 1}},
+			"none": Data{},
+			"zz":   true,
+		},
+		`"http.a b.c"="1" http.method="GET" zz="true"`,
+	},
+	{
+		map[string]interface{}{"m": map[string]interface{}{"a": 1}},
+		`m="map[a:1]"`,
+	},
 }
 
 func TestFormat(t *testing.T) {
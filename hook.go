@@ -0,0 +1,31 @@
+package slog
+
+/*
+A Hook is run on every line logged through the Logger it is added to (and that
+Logger's children) before the line is sent to any target. Hooks may modify the
+line, for instance to add computed fields like the hostname or the current
+goroutine's ID, and may veto it by returning false, in which case it is dropped.
+
+Hooks receive lines after Lazy values and LogValuers have been resolved and
+after snapshots are taken (see Logger.SetSnapshot), but before sequence numbers
+are assigned and before redaction, so fields added by hooks are redacted too.
+Since the values of a line may be shared with the caller, hooks should replace
+values rather than modify them in place.
+*/
+type Hook func(line map[string]interface{}) bool
+
+type hook struct {
+	fn Hook
+}
+
+// Run the given hooks on the record in order, reporting whether they all
+// accepted it.
+func runHooks(hooks []*hook, r *record) bool {
+	m := r.asMap()
+	for _, h := range hooks {
+		if !h.fn(m) {
+			return false
+		}
+	}
+	return true
+}
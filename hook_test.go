@@ -0,0 +1,69 @@
+package slog
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	raw := make(chan map[string]interface{}, 10)
+	root.LogTo(target)
+	root.SetSequence(LoggerSequence)
+
+	var order []string
+	record := func(name string) Hook {
+		return func(line map[string]interface{}) bool {
+			order = append(order, name)
+			return true
+		}
+	}
+	root.AddHook(record("root"))
+	root.AddHook(func(line map[string]interface{}) bool {
+		line["host"] = "example"
+		_, veto := line["veto"]
+		return !veto
+	})
+
+	sub := root.Bind(Data{"a": 1})
+	remove := sub.AddHook(record("sub"))
+	sub.AddHook(func(line map[string]interface{}) bool {
+		line["a"] = fmt.Sprint(line["a"], "!")
+		return true
+	})
+	isolated := sub.Bind(nil)
+	isolated.SetPropagate(false)
+	isolated.SlogTo(raw)
+
+	root.Log(Data{})
+	sub.Log(Data{"veto": true})
+	sub.Log(Data{})
+	remove()
+	sub.Log(Data{})
+	isolated.Warn(Data{})
+
+	expectLines(t, target, []string{
+		`$level="INFO" $seq="1" $time="` + now + `" host="example"` + "\n",
+		`$level="INFO" $seq="2" $time="` + now + `" a="1!" host="example"` + "\n",
+		`$level="INFO" $seq="3" $time="` + now + `" a="1!" host="example"` + "\n",
+	})
+	expected := map[string]interface{}{
+		"$level": LWarn,
+		"$seq":   uint64(4),
+		"$time":  fakeClock(),
+		"a":      "1!",
+		"host":   "example",
+	}
+	if line := <-raw; !reflect.DeepEqual(line, expected) {
+		t.Errorf("Expected %#v, but got %#v", expected, line)
+	}
+	expectedOrder := []string{"root", "root", "root", "sub", "root", "root"}
+	if !reflect.DeepEqual(order, expectedOrder) {
+		t.Errorf("Expected hooks to run in order %v, got %v",
+			expectedOrder, order)
+	}
+}
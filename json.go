@@ -0,0 +1,174 @@
+package slog
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// FormatJSON formats a generic map as a single-line JSON object. Keys are laid
+// out in the same order as with Format, and groups (see Data) are rendered as
+// nested objects. Strings, booleans, and numbers are rendered as their JSON
+// equivalents (except for infinities and NaNs, which JSON cannot represent, and
+// which are rendered as strings), time.Time values are rendered as strings
+// formatted with DefaultTimeFormat, and all other values are rendered as
+// strings formatted using package fmt's "%+v" encoding. Errors are rendered as
+// with Format, except that "key.chain" is an array of messages. Lines are
+// subject to the same limits as with Format.
+func FormatJSON(data map[string]interface{}) string {
+	r := mapRecord(0, data)
+	r.buf = appendJSON(r.buf[:0], r, newLimiter(0))
+	s := string(r.buf)
+	r.release()
+	return s
+}
+
+// Format a record as JSON, enforcing the given limits. The limiter's start is
+// set to where the record's members begin.
+func appendJSON(buf []byte, r *record, lim limiter) []byte {
+	buf = append(buf, '{')
+	lim.start = len(buf)
+	for _, k := range r.orderedKeys() {
+		v, _ := r.get(k)
+		mark := len(buf)
+		buf = appendJSONMember(buf, &lim, k, v)
+		buf = lim.check(buf, mark, k)
+	}
+	if lim.omitted > 0 {
+		buf = appendJSONMember(buf, &lim, omittedField, lim.omitted)
+	}
+	return append(buf, '}', '\n')
+}
+
+// Append a member of an object, expanding errors into one member per detail. If
+// anything has been appended to buf since the start of the object, the member
+// is separated from it by a comma.
+func appendJSONMember(buf []byte, lim *limiter, key string, v interface{}) []byte {
+	if err, ok := v.(error); ok {
+		return appendJSONError(buf, lim, key, err)
+	}
+	buf = appendJSONKey(buf, lim, key)
+	return appendJSONValue(buf, lim, v)
+}
+
+func appendJSONKey(buf []byte, lim *limiter, key string) []byte {
+	if len(buf) > lim.start {
+		buf = append(buf, ',')
+	}
+	buf = appendJSONString(buf, key)
+	return append(buf, ':')
+}
+
+func appendJSONValue(buf []byte, lim *limiter, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendJSONValueString(buf, lim, v)
+	case Level:
+		return appendJSONString(buf, v.String())
+	case time.Time:
+		buf = append(buf, '"')
+		buf = DefaultTimeFormat.AppendFormat(buf, v)
+		return append(buf, '"')
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case float32:
+		return appendJSONFloat(buf, float64(v), 32)
+	case float64:
+		return appendJSONFloat(buf, v, 64)
+	case Data:
+		return appendJSONGroup(buf, lim, v)
+	default:
+		return appendJSONValueString(buf, lim, fmt.Sprintf("%+v", v))
+	}
+}
+
+// Append a string value, truncating it if it's too long.
+func appendJSONValueString(buf []byte, lim *limiter, s string) []byte {
+	s, cut := lim.truncate(s)
+	buf = appendJSONString(buf, s)
+	if cut > 0 {
+		buf = appendTruncated(buf[:len(buf)-1], cut)
+		buf = append(buf, '"')
+	}
+	return buf
+}
+
+func appendJSONFloat(buf []byte, f float64, bits int) []byte {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		buf = append(buf, '"')
+		buf = strconv.AppendFloat(buf, f, 'g', -1, bits)
+		return append(buf, '"')
+	}
+	return strconv.AppendFloat(buf, f, 'g', -1, bits)
+}
+
+func appendJSONGroup(buf []byte, lim *limiter, group Data) []byte {
+	buf = append(buf, '{')
+	inner := *lim
+	inner.start = len(buf)
+	for _, k := range sortedKeys(group) {
+		buf = appendJSONMember(buf, &inner, k, group[k])
+	}
+	return append(buf, '}')
+}
+
+const hex = "0123456789abcdef"
+
+// Append s as a quoted JSON string. Invalid UTF-8 is replaced with U+FFFD.
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0',
+					hex[c>>4], hex[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, `�`...)
+		} else {
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
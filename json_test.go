@@ -0,0 +1,70 @@
+package slog
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+var jsonTests = []struct {
+	line map[string]interface{}
+	out  string
+}{
+	{
+		map[string]interface{}{"hello": 4, "world": "!"},
+		`{"hello":4,"world":"!"}`,
+	},
+	{
+		map[string]interface{}{"foo": 1.2, "bar": false, "baz": nil},
+		`{"bar":false,"baz":null,"foo":1.2}`,
+	},
+	{
+		map[string]interface{}{"inf": math.Inf(1), "u": uint8(7)},
+		`{"inf":"+Inf","u":7}`,
+	},
+	{
+		map[string]interface{}{"\"\n": "\x01\xff世界"},
+		`{"\"\n":"\u0001�世界"}`,
+	},
+	{
+		map[string]interface{}{"=": foo{1, 5}, "d": time.Second},
+		`{"=":"{a:1 b:5}","d":"1s"}`,
+	},
+	{
+		map[string]interface{}{"$level": LWarn, "$time": fakeClock(),
+			"$msg": "hi"},
+		`{"$level":"WARN","$time":"` + now + `","$msg":"hi"}`,
+	},
+	{
+		map[string]interface{}{
+			"http": Data{"method": "GET", "req": Data{"id": 1}},
+			"id":   2,
+			"none": Data{},
+		},
+		`{"http":{"method":"GET","req":{"id":1}},"id":2,"none":{}}`,
+	},
+}
+
+func TestFormatJSON(t *testing.T) {
+	t.Parallel()
+	for _, test := range jsonTests {
+		out := FormatJSON(test.line)
+		if out != test.out+"\n" {
+			t.Errorf("Expected FormatJSON(%v) = %q, got %q",
+				test.line, test.out, out)
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			t.Errorf("FormatJSON(%v) produced invalid JSON: %v",
+				test.line, err)
+		}
+	}
+}
+
+func BenchmarkFormatJSON(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		test := jsonTests[i%len(jsonTests)]
+		FormatJSON(test.line)
+	}
+}
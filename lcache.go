@@ -1,68 +1,147 @@
 package slog
 
 import (
+	"math"
 	"runtime"
 	"strings"
 	"sync"
 )
 
 type levelCache struct {
-	sync.RWMutex
-	iCache map[uintptr]Level
-	parent *levelCache
-	logger *logger
-	rules  rules
+	// Guards writes to iCache. Reads are lock-free: writers copy the map
+	// and atomically swap it in, which is cheap since each call site only
+	// ever gets written once.
+	sync.Mutex
+	iCache *map[uintptr]callSite
+
+	parent     *levelCache
+	logger     *logger
+	rules      rules
+	sampling   samplingRules
+	dedup      *deduper
+	sequence   *sequence
+	redactions redactions
+	snapshot   SnapshotMode
+
+	// The lowest level any rule sets. See cannotLogAt.
+	minLevel Level
 }
 
-func (lc *levelCache) shouldLogAt(level Level) bool {
-	pc, _, _, ok := runtime.Caller(3)
+// Everything we know about a given call site.
+type callSite struct {
+	level   Level
+	sampler *sampler
+}
 
-	// Unclear when this would happen, but let's fail open instead of
-	// closed.
-	if !ok {
-		return true
+// Decide whether the caller should log at the given level. If it should, also
+// return the number of its lines that were previously dropped by sampling. In
+// either case, return the caller's program counter.
+func (lc *levelCache) shouldLogAt(level Level) (uintptr, bool, uint64) {
+	if lc.cannotLogAt(level) {
+		return 0, false, 0
 	}
 
-	lc.RLock()
-	l, ok := lc.iCache[pc]
-	lc.RUnlock()
+	// Skip log, and a method like Debug.
+	pc, site := lc.caller(2)
+	if site.level > level {
+		return pc, false, 0
+	}
+	if site.sampler == nil {
+		return pc, true, 0
+	}
+	ok, dropped := site.sampler.sample(lc.logger.clock())
+	return pc, ok, dropped
+}
 
-	if ok {
-		return l <= level
+// Report whether the caller is configured to log at the given level. Unlike
+// shouldLogAt, this does not count against the caller's sampling policy.
+func (lc *levelCache) enabledAt(level Level) bool {
+	if lc.cannotLogAt(level) {
+		return false
 	}
 
-	f := runtime.FuncForPC(pc)
-	l = lc.levelForFunc(f.Name())
+	// Skip Enabled.
+	_, site := lc.caller(1)
+	return site.level <= level
+}
+
+// Report whether it's impossible for any call site to log at the given level,
+// which lets us avoid figuring out who our caller is.
+func (lc *levelCache) cannotLogAt(level Level) bool {
+	return level < lc.minLevel && level < DefaultLevel
+}
+
+// Find the call site that called our caller, skipping the given number of
+// intermediate frames.
+func (lc *levelCache) caller(skip int) (uintptr, callSite) {
+	// Skip runtime.Callers, ourselves, and our caller.
+	var pcs [1]uintptr
+	if runtime.Callers(3+skip, pcs[:]) == 0 {
+		// Unclear when this would happen, but let's fail open instead
+		// of closed.
+		return 0, callSite{level: math.MinInt32}
+	}
+	pc := pcs[0]
+
+	if site, ok := lc.atomicGetICache()[pc]; ok {
+		return pc, site
+	}
+	return pc, lc.siteForPC(pc)
+}
+
+func (lc *levelCache) siteForPC(pc uintptr) callSite {
+	// Use CallersFrames rather than FuncForPC so that we get the right
+	// answer even if the caller was inlined.
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	site := lc.siteForFunc(pc, frame.Function)
 
 	lc.Lock()
-	lc.iCache[pc] = l
-	lc.Unlock()
+	defer lc.Unlock()
+	old := lc.atomicGetICache()
+	iCache := make(map[uintptr]callSite, len(old)+1)
+	for k, v := range old {
+		iCache[k] = v
+	}
+	iCache[pc] = site
+	lc.atomicSetICache(&iCache)
 
-	return l <= level
+	return site
 }
 
-func (lc *levelCache) levelForFunc(fname string) Level {
+func (lc *levelCache) siteForFunc(pc uintptr, fname string) callSite {
+	site := callSite{level: DefaultLevel}
 	for _, rule := range lc.rules {
-		if !strings.HasPrefix(fname, rule.selector) {
-			continue
+		if selectorMatches(rule.selector, fname) {
+			site.level = rule.level
+			break
 		}
-		ftail := fname[len(rule.selector):]
-		if strings.HasSuffix(rule.selector, ".") {
-			// For pattern "foo.", don't match anything in a
-			// hypothetical oddly-named package "foo.bar".
-			if strings.ContainsRune(ftail, '/') {
-				continue
-			}
-		} else if !strings.HasSuffix(rule.selector, "/") {
-			// For pattern "foo", match "foo.bar" and "foo/bar.baz",
-			// but not "foobar".
-			if len(ftail) > 0 && ftail[0] != '/' && ftail[0] != '.' {
-				continue
-			}
+	}
+	for _, rule := range lc.sampling {
+		if selectorMatches(rule.selector, fname) {
+			site.sampler = rule.sampler(pc)
+			break
 		}
-
-		return rule.level
 	}
+	return site
+}
 
-	return DefaultLevel
+func selectorMatches(selector, fname string) bool {
+	if !strings.HasPrefix(fname, selector) {
+		return false
+	}
+	ftail := fname[len(selector):]
+	if strings.HasSuffix(selector, ".") {
+		// For pattern "foo.", don't match anything in a hypothetical
+		// oddly-named package "foo.bar".
+		if strings.ContainsRune(ftail, '/') {
+			return false
+		}
+	} else if !strings.HasSuffix(selector, "/") {
+		// For pattern "foo", match "foo.bar" and "foo/bar.baz", but not
+		// "foobar".
+		if len(ftail) > 0 && ftail[0] != '/' && ftail[0] != '.' {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,144 @@
+package slog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The names of all known levels. Registries are never modified once they're
+// published, so that they can be read without taking a lock.
+type levelRegistry struct {
+	names  map[Level]string
+	levels map[string]Level
+}
+
+var (
+	levelsLock sync.Mutex
+	levels     = &levelRegistry{
+		names: map[Level]string{
+			LDebug: "DEBUG",
+			LInfo:  "INFO",
+			LWarn:  "WARN",
+			LError: "ERROR",
+		},
+		levels: map[string]Level{
+			"DEBUG": LDebug,
+			"INFO":  LInfo,
+			"WARN":  LWarn,
+			"ERROR": LError,
+		},
+	}
+)
+
+/*
+RegisterLevel registers a custom level with the given name, which is used when
+formatting lines logged at that level and by ParseLevel. Levels are ordered by
+their numeric value, so a level can be placed relative to the built-in ones:
+
+	var LTrace = slog.LDebug - 5
+	var LNotice = slog.LInfo + 5
+
+	func init() {
+		slog.RegisterLevel(LTrace, "TRACE")
+		slog.RegisterLevel(LNotice, "NOTICE")
+	}
+
+Custom levels may be used anywhere the built-in levels can: in selector rules,
+when registering targets, and with Logger.LogAt. Names are case-insensitive and
+are formatted in upper case. RegisterLevel panics if the level or the name is
+already registered to something else.
+*/
+func RegisterLevel(level Level, name string) {
+	name = strings.ToUpper(name)
+	if name == "" {
+		panic("slog: cannot register a level with an empty name")
+	}
+
+	levelsLock.Lock()
+	defer levelsLock.Unlock()
+
+	old := atomicGetLevels()
+	if n, ok := old.names[level]; ok {
+		if n == name {
+			return
+		}
+		panic(fmt.Sprintf("slog: level %d is already registered as %s",
+			int(level), n))
+	}
+	if l, ok := old.levels[name]; ok {
+		panic(fmt.Sprintf("slog: level name %s is already registered "+
+			"for level %d", name, int(l)))
+	}
+
+	reg := &levelRegistry{
+		names:  make(map[Level]string, len(old.names)+1),
+		levels: make(map[string]Level, len(old.levels)+1),
+	}
+	for k, v := range old.names {
+		reg.names[k] = v
+	}
+	for k, v := range old.levels {
+		reg.levels[k] = v
+	}
+	reg.names[level] = name
+	reg.levels[name] = level
+	atomicSetLevels(reg)
+}
+
+// ParseLevel returns the level with the given name, which may be the name of a
+// built-in level or of one registered with RegisterLevel. Names are matched
+// case-insensitively.
+func ParseLevel(name string) (Level, error) {
+	if level, ok := atomicGetLevels().levels[strings.ToUpper(name)]; ok {
+		return level, nil
+	}
+	return 0, fmt.Errorf("slog: unknown level %q", name)
+}
+
+// Levels returns all known levels, including custom ones, from least to most
+// severe.
+func Levels() []Level {
+	reg := atomicGetLevels()
+	ls := make([]Level, 0, len(reg.names))
+	for level := range reg.names {
+		ls = append(ls, level)
+	}
+	sort.Sort(levelSlice(ls))
+	return ls
+}
+
+type levelSlice []Level
+
+func (ls levelSlice) Len() int {
+	return len(ls)
+}
+
+func (ls levelSlice) Less(i, j int) bool {
+	return ls[i] < ls[j]
+}
+
+func (ls levelSlice) Swap(i, j int) {
+	ls[i], ls[j] = ls[j], ls[i]
+}
+
+// String implements the fmt.Stringer interface by returning the level's name:
+// one of DEBUG, INFO, WARN, or ERROR, or the name of a custom level.
+func (l Level) String() string {
+	switch l {
+	case LDebug:
+		return "DEBUG"
+	case LInfo:
+		return "INFO"
+	case LWarn:
+		return "WARN"
+	case LError:
+		return "ERROR"
+	}
+	if name, ok := atomicGetLevels().names[l]; ok {
+		return name
+	}
+	// Unclear how this would happen, but it's probably not nice to panic().
+	return fmt.Sprintf("Level(%d)", l)
+}
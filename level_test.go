@@ -0,0 +1,115 @@
+package slog
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Levels are registered globally, so tests need to use levels that nobody else
+// uses.
+const (
+	lTestTrace  = LDebug - 5
+	lTestNotice = LInfo + 5
+)
+
+func init() {
+	RegisterLevel(lTestTrace, "trace")
+	RegisterLevel(lTestNotice, "NOTICE")
+}
+
+func TestLevelNames(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		level Level
+		name  string
+	}{
+		{LDebug, "DEBUG"},
+		{LError, "ERROR"},
+		{lTestTrace, "TRACE"},
+		{lTestNotice, "NOTICE"},
+		{LWarn + 1, "Level(31)"},
+	}
+	for _, test := range tests {
+		if name := test.level.String(); name != test.name {
+			t.Errorf("Expected %d to be named %q, got %q",
+				test.level, test.name, name)
+		}
+	}
+
+	for _, name := range []string{"notice", "Notice", "NOTICE"} {
+		if level, err := ParseLevel(name); err != nil ||
+			level != lTestNotice {
+			t.Errorf("ParseLevel(%q) = %v, %v", name, level, err)
+		}
+	}
+	if level, err := ParseLevel("warn"); err != nil || level != LWarn {
+		t.Errorf("ParseLevel(warn) = %v, %v", level, err)
+	}
+	if _, err := ParseLevel("bogus"); err == nil {
+		t.Error("Expected an error parsing an unknown level")
+	}
+
+	// Other tests may register levels of their own, so only look at the
+	// ones we know about.
+	expected := []Level{lTestTrace, LDebug, LInfo, lTestNotice, LWarn, LError}
+	var known []Level
+	for _, level := range Levels() {
+		for _, e := range expected {
+			if level == e {
+				known = append(known, level)
+			}
+		}
+	}
+	if !reflect.DeepEqual(known, expected) {
+		t.Errorf("Expected levels %v, got %v", expected, known)
+	}
+}
+
+func expectPanic(t *testing.T, desc string, fn func()) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected %s to panic", desc)
+		}
+	}()
+	fn()
+}
+
+func TestRegisterLevelConflicts(t *testing.T) {
+	t.Parallel()
+	// Registering the same thing twice is fine.
+	RegisterLevel(lTestNotice, "notice")
+	expectPanic(t, "renaming a level", func() {
+		RegisterLevel(lTestNotice, "NOTICE2")
+	})
+	expectPanic(t, "reusing a name", func() {
+		RegisterLevel(LError+1000, "NOTICE")
+	})
+	expectPanic(t, "renaming a built-in level", func() {
+		RegisterLevel(LWarn, "WARNING")
+	})
+	expectPanic(t, "an empty name", func() {
+		RegisterLevel(LError+1001, "")
+	})
+}
+
+func TestLogAt(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	notices := make(chan string, 10)
+	root.LogTo(target)
+	root.LogTo(notices, lTestNotice)
+
+	root.LogAt(lTestTrace, Data{"dropped": true})
+	root.LogAt(lTestNotice, Data{"a": 1})
+	root.SetLevel("github.com/zenazn/slog", lTestTrace)
+	root.LogAt(lTestTrace, Data{"b": 2})
+
+	expectLines(t, notices, []string{
+		`$level="NOTICE" $time="` + now + `" a="1"` + "\n",
+	})
+	expectLines(t, target, []string{
+		`$level="TRACE" $time="` + now + `" b="2"` + "\n",
+	})
+}
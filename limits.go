@@ -0,0 +1,103 @@
+package slog
+
+import (
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Limits on the size of formatted lines, which keep a stray huge value from
+// producing a multi-megabyte line. They are enforced by all of slog's
+// formatters, but do not affect the maps sent to SlogTo targets. A limit of zero
+// (the default) means no limit.
+var (
+	// The maximum length, in bytes, of a string value or of the formatted
+	// form of any other value. Longer values are cut short and marked with
+	// "...(truncated N bytes)".
+	MaxValueLength = 0
+	// The maximum number of fields in a line, not counting system fields.
+	// The fields that come last in formatted order are omitted.
+	MaxFields = 0
+	// The maximum size, in bytes, of a formatted line. Once a line grows
+	// beyond this size, the field that pushed it over and all that follow
+	// are omitted, except for system fields, which are never omitted.
+	MaxRecordSize = 0
+)
+
+// Lines with omitted fields record how many with this system field.
+const omittedField = "$omitted"
+
+var truncations uint64
+
+// Truncations returns the number of values that have been truncated and lines
+// that have had fields omitted because of MaxValueLength, MaxFields, and
+// MaxRecordSize since the process started.
+func Truncations() uint64 {
+	return atomic.LoadUint64(&truncations)
+}
+
+// Cut s down to the maximum value length, returning the number of bytes cut
+// off.
+func (lim *limiter) truncate(s string) (string, int) {
+	if lim.maxValue <= 0 || len(s) <= lim.maxValue {
+		return s, 0
+	}
+	n := lim.maxValue
+	// Don't split a character in half.
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	atomic.AddUint64(&truncations, 1)
+	return s[:n], len(s) - n
+}
+
+func appendTruncated(buf []byte, cut int) []byte {
+	buf = append(buf, "...(truncated "...)
+	buf = strconv.AppendInt(buf, int64(cut), 10)
+	return append(buf, " bytes)"...)
+}
+
+// Keeps track of a line as it's formatted, enforcing the limits. The limits are
+// read once, when formatting starts. The zero limiter has no limits at all,
+// which is useful for formatting lines for internal use.
+type limiter struct {
+	// Where the line (or the object being formatted) begins in the buffer.
+	start int
+
+	maxValue  int
+	maxFields int
+	maxSize   int
+
+	fields  int
+	omitted int
+}
+
+// Return a limiter that enforces the current limits on a line beginning at
+// start.
+func newLimiter(start int) limiter {
+	return limiter{
+		start:     start,
+		maxValue:  MaxValueLength,
+		maxFields: MaxFields,
+		maxSize:   MaxRecordSize,
+	}
+}
+
+// Check the field that was just appended to buf, which began at mark, against
+// the limits, returning buf with the field removed if it exceeds them.
+func (lim *limiter) check(buf []byte, mark int, key string) []byte {
+	if (lim.maxFields <= 0 && lim.maxSize <= 0) || reserved(key) {
+		return buf
+	}
+	lim.fields++
+	if lim.omitted > 0 ||
+		(lim.maxFields > 0 && lim.fields > lim.maxFields) ||
+		(lim.maxSize > 0 && len(buf)-lim.start > lim.maxSize) {
+		if lim.omitted == 0 {
+			atomic.AddUint64(&truncations, 1)
+		}
+		lim.omitted++
+		return buf[:mark]
+	}
+	return buf
+}
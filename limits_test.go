@@ -0,0 +1,105 @@
+package slog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// These tests modify globals, so they can't run in parallel.
+
+func withLimits(value, fields, size int, fn func()) {
+	defer func(value, fields, size int) {
+		MaxValueLength, MaxFields, MaxRecordSize = value, fields, size
+	}(MaxValueLength, MaxFields, MaxRecordSize)
+	MaxValueLength, MaxFields, MaxRecordSize = value, fields, size
+	fn()
+}
+
+var limitTests = []struct {
+	value, fields, size int
+	line                map[string]interface{}
+	out, json           string
+	truncations         uint64
+}{
+	{
+		5, 0, 0,
+		map[string]interface{}{"a": "hello world", "b": "héllo",
+			"c": foo{1, 2}, "d": 1234567, "e": errors.New("kaboom")},
+		`a="hello...(truncated 6 bytes)" b="héll...(truncated 1 bytes)" c="{a:1 ...(truncated 4 bytes)" d="1234567" e="kaboo...(truncated 1 bytes)"`,
+		`{"a":"hello...(truncated 6 bytes)","b":"héll...(truncated 1 bytes)","c":"{a:1 ...(truncated 4 bytes)","d":1234567,"e":"kaboo...(truncated 1 bytes)"}`,
+		8,
+	},
+	{
+		0, 2, 0,
+		map[string]interface{}{"$level": LInfo, "a": 1, "b": 2, "c": 3,
+			"d": 4},
+		`$level="INFO" a="1" b="2" $omitted="2"`,
+		`{"$level":"INFO","a":1,"b":2,"$omitted":2}`,
+		2,
+	},
+	{
+		0, 0, 22,
+		map[string]interface{}{"$level": LInfo, "a": 1, "b": 2,
+			"c": strings.Repeat("x", 20), "d": 4, "~": 5},
+		`$level="INFO" a="1" $omitted="4"`,
+		`{"$level":"INFO","a":1,"$omitted":4}`,
+		2,
+	},
+	{
+		5, 5, 500,
+		map[string]interface{}{"a": "short"},
+		`a="short"`,
+		`{"a":"short"}`,
+		0,
+	},
+}
+
+func TestLimits(t *testing.T) {
+	for _, test := range limitTests {
+		withLimits(test.value, test.fields, test.size, func() {
+			before := Truncations()
+			if out := Format(test.line); out != test.out+"\n" {
+				t.Errorf("Expected Format(%v) = %q, got %q",
+					test.line, test.out, out)
+			}
+			if out := FormatJSON(test.line); out != test.json+"\n" {
+				t.Errorf("Expected FormatJSON(%v) = %q, got %q",
+					test.line, test.json, out)
+			}
+			if n := Truncations() - before; n != test.truncations {
+				t.Errorf("Expected %d truncations formatting %v, "+
+					"got %d", test.truncations, test.line, n)
+			}
+		})
+	}
+}
+
+func TestLimitsFields(t *testing.T) {
+	withLimits(3, 1, 0, func() {
+		root := makeRoot(fakeClock)
+		target := make(chan string, 10)
+		root.LogTo(target)
+		root.LogFields(String("a", "abcdef"), Int("b", 1))
+
+		expectLines(t, target, []string{
+			`$level="INFO" $time="` + now + `" a="abc...(truncated 3 bytes)" $omitted="1"` + "\n",
+		})
+	})
+}
+
+func TestLimitsDedupKey(t *testing.T) {
+	withLimits(3, 1, 10, func() {
+		before := Truncations()
+		a := Data{"body": "abcdef1", "x": 1}
+		b := Data{"body": "abcdef2", "x": 1}
+		c := Data{"body": "abcdef1", "x": 2}
+		if dedupKey(LInfo, 1, a) == dedupKey(LInfo, 1, b) ||
+			dedupKey(LInfo, 1, a) == dedupKey(LInfo, 1, c) {
+			t.Error("Expected dedup keys to ignore the limits")
+		}
+		if n := Truncations() - before; n != 0 {
+			t.Errorf("Expected no truncations, got %d", n)
+		}
+	})
+}
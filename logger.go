@@ -1,17 +1,35 @@
 package slog
 
 import (
+	"fmt"
 	"sort"
 	"sync"
+	"time"
 )
 
 type logger struct {
 	lock          sync.RWMutex
 	parent        *logger
+	clock         func() time.Time
 	context       map[string]interface{}
 	rules         map[string]Level
-	targets       map[Level]func(map[string]interface{})
-	defaultTarget func(map[string]interface{})
+	sampling      map[string]*samplingRule
+	dedup         *deduper
+	sequence      *sequence
+	redactions    redactions
+	snapshot      *SnapshotMode
+	targets       map[Level]*target
+	thresholds    map[Level]*target
+	routes        []route
+	defaultTarget *target
+	hooks         []*hook
+	forward       bool
+	isolated      bool
+
+	// The targets that targets and defaultTarget replaced, most recent
+	// last, so that canceling a target can put the one before it back.
+	replacedTargets  map[Level][]*target
+	replacedDefaults []*target
 
 	lcache *levelCache
 	tcache *targetCache
@@ -23,7 +41,9 @@ type logger struct {
 //   when the atomic set finishes, so whatever cache you happen to atomic get is
 //   by definition valid)
 // - Your cache is identical to a valid cache from your parent
-// - Your cache's parent is identical to a valid cache from your parent
+// - Your cache's parent is identical to a valid cache from your parent, and you
+//   generated the cache yourself (otherwise it might be a stale cache belonging
+//   to your parent that merely happens to have the right parent)
 // In all other cases, you need to grab a lock and generate yourself a new
 // cache.
 func (l *logger) getLCache() *levelCache {
@@ -34,7 +54,7 @@ func (l *logger) getLCache() *levelCache {
 	pcache := l.parent.getLCache()
 	cache := l.atomicGetLCache()
 
-	if cache == pcache || cache.parent == pcache {
+	if cache == pcache || (cache.logger == l && cache.parent == pcache) {
 		return cache
 	}
 
@@ -52,15 +72,55 @@ func (l *logger) getLCache() *levelCache {
 
 // The caller must hold l's mutex.
 func (l *logger) genLCache(pcache *levelCache) *levelCache {
-	if len(l.context) == 0 {
+	if pcache != nil && len(l.rules) == 0 && len(l.sampling) == 0 &&
+		l.dedup == nil && l.sequence == nil && len(l.redactions) == 0 &&
+		l.snapshot == nil {
 		l.atomicSetLCache(pcache)
 		return pcache
 	}
+
+	dedup := l.dedup
+	if dedup == nil && pcache != nil {
+		dedup = pcache.dedup
+	}
+	if dedup != nil && dedup.window <= 0 {
+		dedup = nil
+	}
+
+	seq := l.sequence
+	if seq == nil && pcache != nil {
+		seq = pcache.sequence
+	}
+	if seq != nil && seq.counter == nil {
+		seq = nil
+	}
+
+	snapshot := NoSnapshot
+	if l.snapshot != nil {
+		snapshot = *l.snapshot
+	} else if pcache != nil {
+		snapshot = pcache.snapshot
+	}
+
+	var rds redactions
+	if pcache != nil {
+		rds = append(rds, pcache.redactions...)
+	}
+	rds = append(rds, l.redactions...)
+
+	iCache := make(map[uintptr]callSite)
+	rules := l.generateRules(pcache)
 	lc := &levelCache{
-		iCache: make(map[uintptr]Level),
-		parent: pcache,
-		logger: l,
-		rules:  l.generateRules(pcache),
+		iCache:     &iCache,
+		parent:     pcache,
+		logger:     l,
+		rules:      rules,
+		sampling:   l.generateSampling(pcache),
+		dedup:      dedup,
+		sequence:   seq,
+		redactions: rds,
+		snapshot:   snapshot,
+		minLevel:   rules.minLevel(),
 	}
 	l.atomicSetLCache(lc)
 	return lc
@@ -75,7 +135,7 @@ func (l *logger) getTCache() *targetCache {
 	pcache := l.parent.getTCache()
 	cache := l.atomicGetTCache()
 
-	if cache == pcache || cache.parent == pcache {
+	if cache == pcache || (cache.logger == l && cache.parent == pcache) {
 		return cache
 	}
 
@@ -90,14 +150,23 @@ func (l *logger) getTCache() *targetCache {
 
 // The caller must hold l's mutex.
 func (l *logger) genTCache(pcache *targetCache) *targetCache {
-	if len(l.targets) == 0 && l.defaultTarget == nil {
+	if pcache != nil && !l.isolated && len(l.targets) == 0 &&
+		len(l.thresholds) == 0 && len(l.routes) == 0 &&
+		l.defaultTarget == nil && len(l.hooks) == 0 {
 		l.atomicSetTCache(pcache)
 		return pcache
 	}
 
-	targets := make(map[Level]func(map[string]interface{}))
-	if pcache != nil {
-		for k, v := range pcache.targets {
+	// The cache we inherit routing tables from. It differs from pcache
+	// only when we are isolated from our parent's targets.
+	inherited := pcache
+	if l.isolated {
+		inherited = nil
+	}
+
+	targets := make(map[Level]*target)
+	if inherited != nil {
+		for k, v := range inherited.targets {
 			targets[k] = v
 		}
 	}
@@ -105,23 +174,84 @@ func (l *logger) genTCache(pcache *targetCache) *targetCache {
 		targets[k] = v
 	}
 
-	var defaultTarget func(map[string]interface{})
+	thresholdSet := make(map[Level]*target)
+	if inherited != nil {
+		for _, th := range inherited.thresholds {
+			thresholdSet[th.level] = th.target
+		}
+	}
+	for k, v := range l.thresholds {
+		thresholdSet[k] = v
+	}
+	ths := make(thresholds, 0, len(thresholdSet))
+	for k, v := range thresholdSet {
+		ths = append(ths, threshold{k, v})
+	}
+	sort.Sort(ths)
+
+	// Our routes take precedence over our parent's
+	routes := make([]route, 0, len(l.routes))
+	routes = append(routes, l.routes...)
+	if inherited != nil {
+		routes = append(routes, inherited.routes...)
+	}
+
+	defaultTarget := l.defaultTarget
+	if defaultTarget == nil && inherited != nil {
+		defaultTarget = inherited.defaultTarget
+	}
+
+	// Hooks aren't targets, so they're inherited even if we're isolated.
+	var hooks []*hook
 	if pcache != nil {
-		defaultTarget = pcache.defaultTarget
+		hooks = append(hooks, pcache.hooks...)
 	}
-	if defaultTarget == nil {
-		defaultTarget = l.defaultTarget
+	hooks = append(hooks, l.hooks...)
+
+	forwards := make(map[*target]*targetCache)
+	if inherited != nil {
+		for k, v := range inherited.forwards {
+			forwards[k] = v
+		}
+		if l.forward {
+			for _, t := range l.ownTargets() {
+				forwards[t] = inherited
+			}
+		}
 	}
 
 	tc := &targetCache{
 		targets:       targets,
+		thresholds:    ths,
+		routes:        routes,
 		defaultTarget: defaultTarget,
+		hooks:         hooks,
+		forwards:      forwards,
 		parent:        pcache,
+		logger:        l,
 	}
 	l.atomicSetTCache(tc)
 	return tc
 }
 
+// The caller must hold l's mutex.
+func (l *logger) ownTargets() []*target {
+	var ts []*target
+	for _, t := range l.targets {
+		ts = append(ts, t)
+	}
+	for _, t := range l.thresholds {
+		ts = append(ts, t)
+	}
+	for _, r := range l.routes {
+		ts = append(ts, r.target)
+	}
+	if l.defaultTarget != nil {
+		ts = append(ts, l.defaultTarget)
+	}
+	return ts
+}
+
 func (l *logger) generateRules(pcache *levelCache) rules {
 	rulesSet := make(map[string]Level, len(l.rules))
 	if pcache != nil {
@@ -142,27 +272,100 @@ func (l *logger) generateRules(pcache *levelCache) rules {
 	return rules
 }
 
+func (l *logger) generateSampling(pcache *levelCache) samplingRules {
+	rulesSet := make(map[string]*samplingRule, len(l.sampling))
+	if pcache != nil {
+		for _, rule := range pcache.sampling {
+			rulesSet[rule.selector] = rule
+		}
+	}
+	for k, v := range l.sampling {
+		rulesSet[k] = v
+	}
+
+	rules := make(samplingRules, 0, len(rulesSet))
+	for _, v := range rulesSet {
+		rules = append(rules, v)
+	}
+	sort.Sort(rules)
+
+	return rules
+}
+
 func (l *logger) log(level Level, lines ...map[string]interface{}) bool {
 	cache := l.getLCache()
-	if ok := cache.shouldLogAt(level); !ok {
+	pc, ok, dropped := cache.shouldLogAt(level)
+	if !ok {
 		return false
 	}
 
+	now := l.clock()
 	for _, line := range lines {
-		m := make(map[string]interface{}, len(line)+len(l.context)+1)
-		m["$level"] = level
-		for k, v := range l.context {
-			m[k] = v
-		}
-		for k, v := range line {
-			m[k] = v
-		}
-		l.getTCache().dispatch(level, m)
+		r := newRecord(level, now, l.context)
+		r.line = protectLine(line)
+		l.emit(cache, pc, r, dropped)
+		dropped = 0
+	}
+
+	return true
+}
+
+func (l *logger) logFields(level Level, fields []Field) bool {
+	cache := l.getLCache()
+	pc, ok, dropped := cache.shouldLogAt(level)
+	if !ok {
+		return false
 	}
 
+	r := newRecord(level, l.clock(), l.context)
+	r.fields = protectFields(fields)
+	l.emit(cache, pc, r, dropped)
+
 	return true
 }
 
+func (l *logger) logf(level Level, format string, args []interface{}) bool {
+	cache := l.getLCache()
+	pc, ok, dropped := cache.shouldLogAt(level)
+	if !ok {
+		return false
+	}
+
+	r := newRecord(level, l.clock(), l.context)
+	r.set("$msg", fmt.Sprintf(format, args...))
+	l.emit(cache, pc, r, dropped)
+
+	return true
+}
+
+// Finish off a record logged from the given call site, dispatch it, and release
+// it.
+func (l *logger) emit(cache *levelCache, pc uintptr, r *record, dropped uint64) {
+	defer r.release()
+	if dropped > 0 {
+		r.set("$sampled_out", dropped)
+	}
+	if cache.snapshot != NoSnapshot {
+		cache.snapshot.snapshot(r)
+	}
+	tc := l.getTCache()
+	if len(tc.hooks) > 0 && !runHooks(tc.hooks, r) {
+		return
+	}
+	if len(cache.redactions) > 0 {
+		cache.redactions.redact(r)
+	}
+	if cache.dedup != nil && !cache.dedup.admit(l, pc, r) {
+		return
+	}
+	// Lines are numbered last, so that lines that are never sent don't
+	// leave gaps in the sequence.
+	if cache.sequence != nil {
+		r.set("$seq", cache.sequence.next())
+	}
+	tc.dispatch(r)
+}
+
 func (l *logger) Debug(lines ...map[string]interface{}) bool {
 	return l.log(LDebug, lines...)
 }
@@ -176,6 +379,40 @@ func (l *logger) Error(lines ...map[string]interface{}) bool {
 	return l.log(LError, lines...)
 }
 
+func (l *logger) LogAt(level Level, lines ...map[string]interface{}) bool {
+	return l.log(level, lines...)
+}
+
+func (l *logger) DebugFields(fields ...Field) bool {
+	return l.logFields(LDebug, fields)
+}
+func (l *logger) LogFields(fields ...Field) bool {
+	return l.logFields(LInfo, fields)
+}
+func (l *logger) WarnFields(fields ...Field) bool {
+	return l.logFields(LWarn, fields)
+}
+func (l *logger) ErrorFields(fields ...Field) bool {
+	return l.logFields(LError, fields)
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) bool {
+	return l.logf(LDebug, format, args)
+}
+func (l *logger) Logf(format string, args ...interface{}) bool {
+	return l.logf(LInfo, format, args)
+}
+func (l *logger) Warnf(format string, args ...interface{}) bool {
+	return l.logf(LWarn, format, args)
+}
+func (l *logger) Errorf(format string, args ...interface{}) bool {
+	return l.logf(LError, format, args)
+}
+
+func (l *logger) Enabled(level Level) bool {
+	return l.getLCache().enabledAt(level)
+}
+
 func (l *logger) Bind(context map[string]interface{}) Logger {
 	l.lock.RLock()
 	defer l.lock.RUnlock()
@@ -184,10 +421,46 @@ func (l *logger) Bind(context map[string]interface{}) Logger {
 		ctx[k] = v
 	}
 	for k, v := range context {
+		ctx[protectKey(k)] = v
+	}
+	return l.child(ctx)
+}
+
+func (l *logger) BindGroup(name string, context map[string]interface{}) Logger {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	ctx := make(map[string]interface{}, len(l.context)+1)
+	for k, v := range l.context {
 		ctx[k] = v
 	}
+	name = protectKey(name)
+	group, _ := ctx[name].(Data)
+	ctx[name] = mergeGroups(group, context)
+	return l.child(ctx)
+}
+
+// Merge two groups into a new one, recursively merging any groups they share.
+func mergeGroups(a, b map[string]interface{}) Data {
+	m := make(Data, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		old, oldOk := m[k].(Data)
+		group, ok := v.(Data)
+		if oldOk && ok {
+			v = mergeGroups(old, group)
+		}
+		m[k] = v
+	}
+	return m
+}
+
+// Create a child of l that binds the given context.
+func (l *logger) child(ctx map[string]interface{}) *logger {
 	return &logger{
 		parent:  l,
+		clock:   l.clock,
 		context: ctx,
 		lcache:  l.atomicGetLCache(),
 		tcache:  l.atomicGetTCache(),
@@ -203,6 +476,54 @@ func (l *logger) SetLevel(selector string, level Level) {
 		l.rules[selector] = level
 	}
 
+	l.regenLCache()
+}
+
+func (l *logger) SetSampling(selector string, sampling Sampling) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.sampling == nil {
+		l.sampling = make(map[string]*samplingRule)
+	}
+	l.sampling[selector] = newSamplingRule(selector, sampling)
+	l.regenLCache()
+}
+
+func (l *logger) SetDedup(window time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	// Even a disabled deduper needs to be recorded so that it overrides
+	// our parent's.
+	l.dedup = newDeduper(window)
+	l.regenLCache()
+}
+
+func (l *logger) SetSequence(scope SequenceScope) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.sequence = newSequence(scope)
+	l.regenLCache()
+}
+
+func (l *logger) SetSnapshot(mode SnapshotMode) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.snapshot = &mode
+	l.regenLCache()
+}
+
+func (l *logger) Redact(policy Redaction) {
+	rd := newRedaction(policy)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	// Copy rather than append, since caches may share our old slice.
+	rds := make(redactions, 0, len(l.redactions)+1)
+	l.redactions = append(append(rds, l.redactions...), rd)
+	l.regenLCache()
+}
+
+// The caller must hold l's mutex.
+func (l *logger) regenLCache() {
 	var pcache *levelCache
 	if l.parent != nil {
 		pcache = l.parent.getLCache()
@@ -210,33 +531,201 @@ func (l *logger) SetLevel(selector string, level Level) {
 	l.genLCache(pcache)
 }
 
-func (l *logger) LogTo(ch chan<- string, levels ...Level) {
+func (l *logger) SetForward(forward bool) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	l.setTarget(func(line map[string]interface{}) {
-		ch <- Format(line)
-	}, levels)
+	l.forward = forward
+	l.regenTCache()
 }
 
-func (l *logger) SlogTo(ch chan<- map[string]interface{}, levels ...Level) {
+func (l *logger) SetPropagate(propagate bool) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	l.setTarget(func(line map[string]interface{}) {
-		ch <- line
-	}, levels)
+	l.isolated = !propagate
+	l.regenTCache()
 }
 
-func (l *logger) setTarget(fn func(map[string]interface{}), levels []Level) {
-	if len(levels) == 0 {
-		l.defaultTarget = fn
+func formatTo(ch chan<- string) func(*record) {
+	return func(r *record) {
+		ch <- r.format()
 	}
-	if l.targets == nil {
-		l.targets = make(map[Level]func(map[string]interface{}))
+}
+
+func sendTo(ch chan<- map[string]interface{}) func(*record) {
+	return func(r *record) {
+		m := r.asMap()
+		if r.shared {
+			cp := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				cp[k] = v
+			}
+			m = cp
+		}
+		ch <- m
 	}
-	for _, level := range levels {
-		l.targets[level] = fn
+}
+
+func (l *logger) LogTo(ch chan<- string, levels ...Level) func() {
+	return l.addTarget(formatTo(ch), levels)
+}
+
+func (l *logger) SlogTo(ch chan<- map[string]interface{}, levels ...Level) func() {
+	return l.addTarget(sendTo(ch), levels)
+}
+
+func (l *logger) LogAtLeast(ch chan<- string, level Level) func() {
+	return l.addThreshold(formatTo(ch), level)
+}
+
+func (l *logger) SlogAtLeast(ch chan<- map[string]interface{}, level Level) func() {
+	return l.addThreshold(sendTo(ch), level)
+}
+
+func (l *logger) LogWhen(ch chan<- string, pred Predicate) func() {
+	return l.addRoute(formatTo(ch), pred)
+}
+
+func (l *logger) SlogWhen(ch chan<- map[string]interface{}, pred Predicate) func() {
+	return l.addRoute(sendTo(ch), pred)
+}
+
+func (l *logger) AddHook(fn Hook) func() {
+	h := &hook{fn}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.hooks = append(l.hooks, h)
+	l.regenTCache()
+
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		hooks := make([]*hook, 0, len(l.hooks))
+		for _, other := range l.hooks {
+			if other != h {
+				hooks = append(hooks, other)
+			}
+		}
+		l.hooks = hooks
+		l.regenTCache()
+	}
+}
+
+func (l *logger) addTarget(fn func(*record), levels []Level) func() {
+	return l.attach(fn, func(t *target) {
+		if len(levels) == 0 {
+			if l.defaultTarget != nil {
+				l.replacedDefaults = append(l.replacedDefaults,
+					l.defaultTarget)
+			}
+			l.defaultTarget = t
+		}
+		if l.targets == nil {
+			l.targets = make(map[Level]*target)
+			l.replacedTargets = make(map[Level][]*target)
+		}
+		for _, level := range levels {
+			if old, ok := l.targets[level]; ok {
+				l.replacedTargets[level] = append(
+					l.replacedTargets[level], old)
+			}
+			l.targets[level] = t
+		}
+	}, func(t *target) {
+		if len(levels) == 0 {
+			l.defaultTarget, l.replacedDefaults = unlinkTarget(
+				l.defaultTarget, l.replacedDefaults, t)
+		}
+		for _, level := range levels {
+			cur, replaced := unlinkTarget(l.targets[level],
+				l.replacedTargets[level], t)
+			if cur == nil {
+				delete(l.targets, level)
+			} else {
+				l.targets[level] = cur
+			}
+			if len(replaced) == 0 {
+				delete(l.replacedTargets, level)
+			} else {
+				l.replacedTargets[level] = replaced
+			}
+		}
+	})
+}
+
+// Remove t from a slot whose current target is cur and which previously held
+// the targets in replaced. If t is current, the target it replaced takes its
+// place; otherwise it's simply forgotten.
+func unlinkTarget(cur *target, replaced []*target,
+	t *target) (*target, []*target) {
+
+	if cur == t {
+		if len(replaced) == 0 {
+			return nil, nil
+		}
+		return replaced[len(replaced)-1], replaced[:len(replaced)-1]
 	}
+	for i, old := range replaced {
+		if old == t {
+			rest := make([]*target, 0, len(replaced)-1)
+			rest = append(rest, replaced[:i]...)
+			return cur, append(rest, replaced[i+1:]...)
+		}
+	}
+	return cur, replaced
+}
 
+func (l *logger) addThreshold(fn func(*record), level Level) func() {
+	return l.attach(fn, func(t *target) {
+		if l.thresholds == nil {
+			l.thresholds = make(map[Level]*target)
+		}
+		l.thresholds[level] = t
+	}, func(t *target) {
+		if l.thresholds[level] == t {
+			delete(l.thresholds, level)
+		}
+	})
+}
+
+func (l *logger) addRoute(fn func(*record), pred Predicate) func() {
+	return l.attach(fn, func(t *target) {
+		l.routes = append(l.routes, route{pred, t})
+	}, func(t *target) {
+		routes := make([]route, 0, len(l.routes))
+		for _, r := range l.routes {
+			if r.target != t {
+				routes = append(routes, r)
+			}
+		}
+		l.routes = routes
+	})
+}
+
+// Create a new target from fn and attach it to l, returning a function that
+// detaches it again. link and unlink are called with l's mutex held, and should
+// add and remove the target from l's routing tables.
+func (l *logger) attach(fn func(*record), link, unlink func(*target)) func() {
+	t := &target{fn: fn}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	link(t)
+	l.regenTCache()
+
+	return func() {
+		l.lock.Lock()
+		unlink(t)
+		l.regenTCache()
+		l.lock.Unlock()
+
+		// Some goroutines might still hold a stale cache that contains
+		// t, so unlinking it isn't enough: we also need to wait out
+		// anyone who is in the middle of sending to it.
+		t.cancel()
+	}
+}
+
+// The caller must hold l's mutex.
+func (l *logger) regenTCache() {
 	var pcache *targetCache
 	if l.parent != nil {
 		pcache = l.parent.getTCache()
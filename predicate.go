@@ -0,0 +1,85 @@
+package slog
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Predicate decides whether a log line should be sent to a target registered
+// with LogWhen or SlogWhen. Predicates are run on every line that is logged
+// through the Logger they are attached to (or any of its children), so they
+// should be fast, and they must not modify the line.
+type Predicate func(line Line) bool
+
+// A Line is a log line as seen by a Predicate. Looking up its fields one at a
+// time with Get is much cheaper than asking for all of them with Map.
+type Line struct {
+	r *record
+}
+
+// Get returns the value of the given field, and whether the line has it.
+func (l Line) Get(key string) (interface{}, bool) {
+	return l.r.get(key)
+}
+
+// Map returns all of the line's fields, as they would be sent to a target
+// registered with SlogTo. The map must not be modified.
+func (l Line) Map() map[string]interface{} {
+	return l.r.asMap()
+}
+
+// HasKey returns a Predicate that matches lines that contain the given key.
+func HasKey(key string) Predicate {
+	return func(line Line) bool {
+		_, ok := line.Get(key)
+		return ok
+	}
+}
+
+// Equals returns a Predicate that matches lines whose value for the given key is
+// equal (in the sense of ==) to the given value. The value must be comparable.
+func Equals(key string, value interface{}) Predicate {
+	if value != nil && !reflect.TypeOf(value).Comparable() {
+		panic(fmt.Sprintf("slog: Equals value %#v is not comparable",
+			value))
+	}
+	return func(line Line) bool {
+		v, ok := line.Get(key)
+		// Since value is comparable, this can only panic if v is of the
+		// same type as value, which means it can't panic.
+		return ok && v == value
+	}
+}
+
+// And returns a Predicate that matches lines that match all of the given
+// Predicates.
+func And(preds ...Predicate) Predicate {
+	return func(line Line) bool {
+		for _, p := range preds {
+			if !p(line) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a Predicate that matches lines that match any of the given
+// Predicates.
+func Or(preds ...Predicate) Predicate {
+	return func(line Line) bool {
+		for _, p := range preds {
+			if p(line) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a Predicate that matches lines that the given Predicate does not.
+func Not(pred Predicate) Predicate {
+	return func(line Line) bool {
+		return !pred(line)
+	}
+}
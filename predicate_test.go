@@ -0,0 +1,74 @@
+package slog
+
+import (
+	"testing"
+	"time"
+)
+
+var predicateTests = []struct {
+	pred Predicate
+	line map[string]interface{}
+	out  bool
+}{
+	{HasKey("err"), Data{"err": nil}, true},
+	{HasKey("err"), Data{"error": "oops"}, false},
+	{Equals("component", "billing"), Data{"component": "billing"}, true},
+	{Equals("component", "billing"), Data{"component": "search"}, false},
+	{Equals("component", "billing"), Data{}, false},
+	{Equals("n", 4), Data{"n": int64(4)}, false},
+	{Equals("n", 4), Data{"n": []int{4}}, false},
+	{Equals("$level", LError), Data{"$level": LError}, true},
+	{And(HasKey("a"), HasKey("b")), Data{"a": 1, "b": 2}, true},
+	{And(HasKey("a"), HasKey("b")), Data{"a": 1}, false},
+	{And(), Data{}, true},
+	{Or(HasKey("a"), HasKey("b")), Data{"b": 2}, true},
+	{Or(HasKey("a"), HasKey("b")), Data{"c": 3}, false},
+	{Or(), Data{}, false},
+	{Not(HasKey("a")), Data{"a": 1}, false},
+	{Not(HasKey("a")), Data{}, true},
+}
+
+func TestPredicates(t *testing.T) {
+	t.Parallel()
+	for i, test := range predicateTests {
+		r := newRecord(LInfo, time.Time{}, nil)
+		r.line = test.line
+		if out := test.pred(Line{r}); out != test.out {
+			t.Errorf("Expected predicate %d on %v to be %v", i,
+				test.line, test.out)
+		}
+		r.release()
+	}
+}
+
+func TestLineMap(t *testing.T) {
+	t.Parallel()
+	r := newRecord(LInfo, fakeClock(), Data{"a": 1})
+	defer r.release()
+	r.line = Data{"b": 2}
+	m := Line{r}.Map()
+	if m["a"] != 1 || m["b"] != 2 || m["$level"] != LInfo ||
+		m["$time"] != fakeClock() {
+		t.Errorf("Unexpected map %v", m)
+	}
+}
+
+func TestEqualsUncomparable(t *testing.T) {
+	t.Parallel()
+	expectPanic(t, "Equals with an uncomparable value", func() {
+		Equals("n", []int{4})
+	})
+}
+
+func BenchmarkPredicate(b *testing.B) {
+	pred := Or(And(Equals("component", "billing"), Not(HasKey("test"))),
+		HasKey("err"))
+	r := newRecord(LInfo, fakeClock(), nil)
+	r.line = Data{"component": "billing", "hello": "world", "a": 1}
+	defer r.release()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pred(Line{r})
+	}
+}
@@ -0,0 +1,337 @@
+package slog
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+A record is a single log line on its way to its targets. Rather than eagerly
+copying the Logger's context and the line's fields into a single map, a record
+keeps them separate, and only merges them if a target asks for a map. Targets
+which only want the formatted line can be served without building one, and
+since records are pooled, a line which is only formatted costs little more than
+the string it is formatted into.
+
+A line is either a map or a slice of Fields. Fields set on the line take
+precedence over those bound in the context, which in turn take precedence over
+system fields (though keys that clash with system fields are renamed before they
+ever make it into a record; see ReservedPrefix).
+
+Records may only be used by the goroutine that is logging them, and targets must
+not retain them.
+*/
+type record struct {
+	level Level
+	// When the line was logged. This is the "$time" system field, but it's
+	// stored separately so that it can be formatted without boxing it.
+	time    time.Time
+	sys     []kv
+	context map[string]interface{}
+	line    map[string]interface{}
+	fields  []Field
+
+	// The merged map, once someone has asked for it. Once it exists, it is
+	// authoritative.
+	m map[string]interface{}
+	// The formatted line, once someone has asked for it.
+	formatted string
+	// Whether the record is going to more than one target, in which case
+	// none of them may have m for themselves.
+	shared bool
+	// The values of fields we've resolved so far (see resolveValue).
+	resolved []kv
+
+	// Scratch space for formatting.
+	keys []string
+	buf  []byte
+}
+
+type kv struct {
+	key   string
+	value interface{}
+}
+
+var recordPool = sync.Pool{
+	New: func() interface{} {
+		return new(record)
+	},
+}
+
+func newRecord(level Level, t time.Time, context map[string]interface{}) *record {
+	r := recordPool.Get().(*record)
+	r.level = level
+	r.time = t
+	r.sys = append(r.sys, kv{"$level", level})
+	r.context = context
+	return r
+}
+
+// Create a record that wraps an existing map. The map becomes the record's merged
+// map, so the caller must not modify it afterwards.
+func mapRecord(level Level, m map[string]interface{}) *record {
+	r := recordPool.Get().(*record)
+	r.level = level
+	r.m = m
+	return r
+}
+
+func (r *record) release() {
+	// Don't hang on to unreasonably large buffers forever.
+	if cap(r.buf) > 64<<10 {
+		r.buf = nil
+	}
+	for i := range r.sys {
+		r.sys[i] = kv{}
+	}
+	for i := range r.resolved {
+		r.resolved[i] = kv{}
+	}
+	*r = record{
+		sys:      r.sys[:0],
+		resolved: r.resolved[:0],
+		keys:     r.keys[:0],
+		buf:      r.buf[:0],
+	}
+	recordPool.Put(r)
+}
+
+// Add a system field to the record.
+func (r *record) set(key string, value interface{}) {
+	if r.m != nil {
+		r.m[key] = value
+	} else {
+		r.sys = append(r.sys, kv{key, value})
+	}
+}
+
+// Get the value of the given field, resolving it if necessary.
+func (r *record) get(key string) (interface{}, bool) {
+	v, ok := r.lookup(key)
+	if needsResolving(v, 0) {
+		v = r.resolve(key, v)
+	}
+	return v, ok
+}
+
+func (r *record) lookup(key string) (interface{}, bool) {
+	if r.m != nil {
+		v, ok := r.m[key]
+		return v, ok
+	}
+	if i := r.fieldIndex(key); i >= 0 {
+		return r.fields[i].Value(), true
+	}
+	if v, ok := r.line[key]; ok {
+		return v, true
+	}
+	if v, ok := r.context[key]; ok {
+		return v, true
+	}
+	for _, f := range r.sys {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+	if key == "$time" && !r.time.IsZero() {
+		return r.time, true
+	}
+	return nil, false
+}
+
+// If the given field is the record's unboxed timestamp, return it.
+func (r *record) timeField(key string) (time.Time, bool) {
+	if key != "$time" || r.m != nil || r.time.IsZero() || r.shadowed(key) {
+		return time.Time{}, false
+	}
+	return r.time, true
+}
+
+// Find the index of the last Field with the given key, or -1 if there is none.
+func (r *record) fieldIndex(key string) int {
+	for i := len(r.fields) - 1; i >= 0; i-- {
+		if r.fields[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Report whether the given key is set on the line itself.
+func (r *record) inLine(key string) bool {
+	if _, ok := r.line[key]; ok {
+		return true
+	}
+	return r.fieldIndex(key) >= 0
+}
+
+// Report whether the given system field is overridden by the line or context.
+func (r *record) shadowed(key string) bool {
+	if r.inLine(key) {
+		return true
+	}
+	_, ok := r.context[key]
+	return ok
+}
+
+// Return the record's keys in the order they should be formatted: sorted, unless
+// PreserveFieldOrder asks otherwise. The returned slice is only valid until the
+// next call to orderedKeys.
+func (r *record) orderedKeys() []string {
+	keys := r.keys[:0]
+	inOrder := PreserveFieldOrder && len(r.fields) > 0
+	if r.m != nil {
+		for k := range r.m {
+			if !inOrder || r.fieldIndex(k) < 0 {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		placeMsg(keys)
+		if inOrder {
+			keys = r.appendFieldKeys(keys)
+		}
+		r.keys = keys
+		return keys
+	}
+
+	if !inOrder {
+		keys = r.appendFieldKeys(keys)
+	}
+	for k := range r.line {
+		keys = append(keys, k)
+	}
+	for k := range r.context {
+		if !r.inLine(k) {
+			keys = append(keys, k)
+		}
+	}
+	for _, f := range r.sys {
+		if !r.shadowed(f.key) {
+			keys = append(keys, f.key)
+		}
+	}
+	if !r.time.IsZero() && !r.shadowed("$time") {
+		keys = append(keys, "$time")
+	}
+	sort.Strings(keys)
+	placeMsg(keys)
+	if inOrder {
+		keys = r.appendFieldKeys(keys)
+	}
+	r.keys = keys
+	return keys
+}
+
+// Move "$msg" in a sorted slice of keys so that it comes right after the other
+// system fields, which makes it easy to find when reading logs.
+func placeMsg(keys []string) {
+	i := sort.SearchStrings(keys, "$msg")
+	if i == len(keys) || keys[i] != "$msg" {
+		return
+	}
+	j := i
+	for j+1 < len(keys) && strings.HasPrefix(keys[j+1], "$") {
+		keys[j] = keys[j+1]
+		j++
+	}
+	keys[j] = "$msg"
+}
+
+// Append the keys of the record's Fields in order, skipping duplicates.
+func (r *record) appendFieldKeys(keys []string) []string {
+	for i, f := range r.fields {
+		dup := false
+		for _, g := range r.fields[:i] {
+			if g.Key == f.Key {
+				dup = true
+				break
+			}
+		}
+		if dup {
+			continue
+		}
+		// Once there's a merged map, it's authoritative: a hook might
+		// have deleted the field.
+		if _, ok := r.m[f.Key]; r.m != nil && !ok {
+			continue
+		}
+		keys = append(keys, f.Key)
+	}
+	return keys
+}
+
+// Append the given key and its value, formatted as with Format. If anything has
+// been appended to buf since the start of the line, the pair is separated from it
+// by a space.
+func (r *record) appendField(buf []byte, lim *limiter, key string) []byte {
+	if r.m == nil {
+		i := r.fieldIndex(key)
+		if i >= 0 && r.fields[i].kind != anyKind {
+			buf = appendKey(buf, lim, key)
+			return r.fields[i].appendValue(buf, lim)
+		}
+		if t, ok := r.timeField(key); ok {
+			buf = appendKey(buf, lim, key)
+			return appendTime(buf, t)
+		}
+	}
+	v, _ := r.get(key)
+	return appendPair(buf, lim, key, v)
+}
+
+// asMap returns the record as a single map, building it if necessary. The map is
+// shared by everyone who asks for it.
+func (r *record) asMap() map[string]interface{} {
+	if r.m != nil {
+		return r.m
+	}
+	m := make(map[string]interface{},
+		len(r.sys)+len(r.context)+len(r.line)+len(r.fields)+1)
+	if !r.time.IsZero() {
+		m["$time"] = r.time
+	}
+	for _, f := range r.sys {
+		m[f.key] = f.value
+	}
+	for k, v := range r.context {
+		m[k] = v
+	}
+	for k, v := range r.line {
+		m[k] = v
+	}
+	for _, f := range r.fields {
+		m[f.Key] = f.Value()
+	}
+	for k, v := range m {
+		if needsResolving(v, 0) {
+			m[k] = r.resolve(k, v)
+		}
+	}
+	r.m = m
+	return m
+}
+
+// Resolve the value of the given field, making sure to only ever do so once
+// even if the record is sent to several targets.
+func (r *record) resolve(key string, value interface{}) interface{} {
+	for _, f := range r.resolved {
+		if f.key == key {
+			return f.value
+		}
+	}
+	v := resolveValue(value, 0)
+	r.resolved = append(r.resolved, kv{key, v})
+	return v
+}
+
+// format returns the record formatted as with the package-level Format.
+func (r *record) format() string {
+	if r.formatted == "" {
+		r.buf = appendFormat(r.buf[:0], r, newLimiter(0))
+		r.formatted = string(r.buf)
+	}
+	return r.formatted
+}
@@ -0,0 +1,278 @@
+package slog
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Redaction is a policy that keeps sensitive values out of the logs. A value is
+replaced with Replacement if its key matches one of the Keys, which are
+case-insensitive glob patterns with the syntax of path.Match, except that "*"
+and "?" match "/" like any other character (for instance, "*password*" matches
+"db/password"), or if its type is one of the Types (or, for interface types,
+implements it). Additionally, every match of one of the Values within a string
+value is replaced with Replacement, which is useful for things like credit card
+numbers that might appear anywhere.
+
+Redaction applies to the values of groups (see Data), whose fields are matched
+by their own keys, and to messages logged with methods like Logf, but not to
+other system fields. Errors are redacted too: the message of an error and of
+the errors it wraps are subject to Values, and the fields of those that
+implement LogFields (see Format) are redacted like a group. An error with
+anything to redact is replaced with one that carries the redacted details.
+Values are redacted after Lazy values and LogValuers are resolved. An empty
+Replacement is treated as "[REDACTED]".
+*/
+type Redaction struct {
+	Keys        []string
+	Types       []reflect.Type
+	Values      []*regexp.Regexp
+	Replacement string
+}
+
+type redaction struct {
+	keys        []*regexp.Regexp
+	types       []reflect.Type
+	values      []*regexp.Regexp
+	replacement string
+}
+
+func newRedaction(policy Redaction) *redaction {
+	rd := &redaction{
+		types:       policy.Types,
+		values:      policy.Values,
+		replacement: policy.Replacement,
+	}
+	if rd.replacement == "" {
+		rd.replacement = "[REDACTED]"
+	}
+	for _, key := range policy.Keys {
+		re, err := compileGlob(key)
+		if err != nil {
+			panic(fmt.Sprintf("slog: bad redaction pattern %q: %v",
+				key, err))
+		}
+		rd.keys = append(rd.keys, re)
+	}
+	return rd
+}
+
+// Compile a case-insensitive glob pattern into a regular expression. The syntax
+// is that of path.Match, but since keys aren't paths, "/" isn't special.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '\\':
+			i++
+			if i == len(pattern) {
+				return nil, path.ErrBadPattern
+			}
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			n, err := appendClass(&sb, pattern[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			i += n
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Translate the character class at the start of pattern, which follows a "[",
+// into a regular expression, returning how much of pattern it used. Classes
+// have the syntax of path.Match, which we parse ourselves so that the syntax of
+// regular expression classes can't sneak through.
+func appendClass(sb *strings.Builder, pattern string) (int, error) {
+	i := 0
+	negated := strings.HasPrefix(pattern, "^")
+	if negated {
+		i++
+	}
+	var ranges strings.Builder
+	// Read a possibly escaped character of a range.
+	char := func() (rune, error) {
+		if i == len(pattern) || pattern[i] == '-' || pattern[i] == ']' {
+			return 0, path.ErrBadPattern
+		}
+		if pattern[i] == '\\' {
+			i++
+			if i == len(pattern) {
+				return 0, path.ErrBadPattern
+			}
+		}
+		c, n := utf8.DecodeRuneInString(pattern[i:])
+		i += n
+		return c, nil
+	}
+	for {
+		lo, err := char()
+		if err != nil {
+			return 0, err
+		}
+		hi := lo
+		if i < len(pattern) && pattern[i] == '-' {
+			i++
+			if hi, err = char(); err != nil {
+				return 0, err
+			}
+		}
+		// path.Match accepts backwards ranges, which match nothing.
+		if lo <= hi {
+			fmt.Fprintf(&ranges, `\x{%x}-\x{%x}`, lo, hi)
+		}
+		if i < len(pattern) && pattern[i] == ']' {
+			break
+		}
+	}
+	switch {
+	case ranges.Len() > 0 && negated:
+		sb.WriteString("[^" + ranges.String() + "]")
+	case ranges.Len() > 0:
+		sb.WriteString("[" + ranges.String() + "]")
+	case negated:
+		sb.WriteString(".")
+	default:
+		sb.WriteString(`[^\x00-\x{10ffff}]`)
+	}
+	return i + 1, nil
+}
+
+// A set of redactions, applied in order.
+type redactions []*redaction
+
+// Redact the values of the given record.
+func (rs redactions) redact(r *record) {
+	m := r.asMap()
+	for k, v := range m {
+		if reserved(k) {
+			if s, ok := v.(string); ok && k == "$msg" {
+				m[k] = rs.redactString(s)
+			}
+			continue
+		}
+		if redacted, ok := rs.redactValue(k, v, 0); ok {
+			m[k] = redacted
+		}
+	}
+}
+
+// Redact the given value, reporting whether anything changed.
+func (rs redactions) redactValue(key string, v interface{},
+	depth int) (interface{}, bool) {
+
+	if rep, ok := rs.match(key, v); ok {
+		return rep, true
+	}
+	switch value := v.(type) {
+	case string:
+		s := rs.redactString(value)
+		return s, s != value
+	case error:
+		return rs.redactError(value, depth)
+	case Data:
+		if depth >= MaxLogValueDepth {
+			return errLogValueDepth, true
+		}
+		// Groups may belong to the caller, so they need to be copied
+		// rather than modified.
+		var group Data
+		for k, inner := range value {
+			redacted, ok := rs.redactValue(k, inner, depth+1)
+			if !ok {
+				continue
+			}
+			if group == nil {
+				group = make(Data, len(value))
+				for k2, v2 := range value {
+					group[k2] = v2
+				}
+			}
+			group[k] = redacted
+		}
+		if group != nil {
+			return group, true
+		}
+	}
+	return v, false
+}
+
+// An error whose details have been redacted. describeError knows to use its
+// details as they are.
+type redactedError struct {
+	detail errorDetail
+}
+
+func (e *redactedError) Error() string {
+	return e.detail.msg
+}
+
+// Redact the details of an error, reporting whether anything changed.
+func (rs redactions) redactError(err error, depth int) (interface{}, bool) {
+	d := describeError(err)
+	changed := false
+	if msg := rs.redactString(d.msg); msg != d.msg {
+		d.msg, changed = msg, true
+	}
+	for i, msg := range d.chain {
+		if redacted := rs.redactString(msg); redacted != msg {
+			d.chain[i], changed = redacted, true
+		}
+	}
+	// We own d.fields, so we can modify it as we please.
+	for k, v := range d.fields {
+		if redacted, ok := rs.redactValue(k, v, depth+1); ok {
+			d.fields[k], changed = redacted, true
+		}
+	}
+	if !changed {
+		return err, false
+	}
+	return &redactedError{d}, true
+}
+
+// Report whether the given value is to be replaced wholesale, and if so, with
+// what.
+func (rs redactions) match(key string, v interface{}) (string, bool) {
+	for _, rd := range rs {
+		for _, pattern := range rd.keys {
+			if pattern.MatchString(key) {
+				return rd.replacement, true
+			}
+		}
+		if v == nil {
+			continue
+		}
+		t := reflect.TypeOf(v)
+		for _, rt := range rd.types {
+			if t == rt || (rt.Kind() == reflect.Interface &&
+				t.Implements(rt)) {
+				return rd.replacement, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (rs redactions) redactString(s string) string {
+	for _, rd := range rs {
+		for _, re := range rd.values {
+			s = re.ReplaceAllLiteralString(s, rd.replacement)
+		}
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+package slog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+type secret string
+
+type credential interface {
+	Credential()
+}
+
+type apiKey struct {
+	key string
+}
+
+func (apiKey) Credential() {}
+
+var cardNumber = regexp.MustCompile(`\b\d{4}(?:[ -]?\d{4}){3}\b`)
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	raw := make(chan map[string]interface{}, 10)
+	root.LogTo(target)
+	root.Redact(Redaction{Keys: []string{"*password*", "Authorization"}})
+
+	sub := root.Bind(Data{"user_Password": "hunter2"})
+	sub.Redact(Redaction{
+		Types: []reflect.Type{
+			reflect.TypeOf(secret("")),
+			reflect.TypeOf((*credential)(nil)).Elem(),
+		},
+		Values:      []*regexp.Regexp{cardNumber},
+		Replacement: "***",
+	})
+	sub.SlogTo(raw, LWarn)
+
+	headers := Data{"authorization": "Bearer xyz", "accept": "*/*"}
+	sub.Log(Data{"headers": headers, "s": secret("shh"),
+		"k": apiKey{"123"}, "note": "card 1234 5678 9012 3456 ok"})
+	sub.LogFields(String("AUTHORIZATION", "x"), Int("n", 1))
+	sub.Logf("paid with %s", "1234-5678-9012-3456")
+	root.Log(Data{"password": "hunter2", "card": "1234567890123456"})
+	sub.Warn(Data{"headers": headers})
+
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" headers.accept="*/*" headers.authorization="[REDACTED]" k="***" note="card *** ok" s="***" user_Password="[REDACTED]"` + "\n",
+		`$level="INFO" $time="` + now + `" AUTHORIZATION="[REDACTED]" n="1" user_Password="[REDACTED]"` + "\n",
+		`$level="INFO" $time="` + now + `" $msg="paid with ***" user_Password="[REDACTED]"` + "\n",
+		`$level="INFO" $time="` + now + `" card="1234567890123456" password="[REDACTED]"` + "\n",
+	})
+	expected := map[string]interface{}{
+		"$level":        LWarn,
+		"$time":         fakeClock(),
+		"headers":       Data{"authorization": "[REDACTED]", "accept": "*/*"},
+		"user_Password": "[REDACTED]",
+	}
+	if line := <-raw; !reflect.DeepEqual(line, expected) {
+		t.Errorf("Expected %#v, but got %#v", expected, line)
+	}
+	if headers["authorization"] != "Bearer xyz" {
+		t.Error("Expected the caller's group not to be modified")
+	}
+}
+
+type loginError struct {
+	password string
+}
+
+func (e loginError) Error() string {
+	return "login failed for card 1234 5678 9012 3456"
+}
+
+func (e loginError) LogFields() map[string]interface{} {
+	return map[string]interface{}{"password": e.password, "user": "bob"}
+}
+
+func TestRedactErrors(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	root.LogTo(target)
+	root.Redact(Redaction{
+		Keys:   []string{"*password*"},
+		Values: []*regexp.Regexp{cardNumber},
+	})
+
+	err := fmt.Errorf("oops: %w", loginError{"hunter2"})
+	root.Log(Data{"err": err, "db/password": "hunter2"})
+	root.LogFields(Err(errors.New("nothing to see")))
+
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" db/password="[REDACTED]" err="oops: login failed for card [REDACTED]" err.chain.0="login failed for card [REDACTED]" err.password="[REDACTED]" err.user="bob"` + "\n",
+		`$level="INFO" $time="` + now + `" err="nothing to see"` + "\n",
+	})
+}
+
+func TestGlobs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		pattern, key string
+		match        bool
+	}{
+		{"*password*", "db/password", true},
+		{"*password*", "User_PASSWORD_hash", true},
+		{"authorization", "Authorization", true},
+		{"authorization", "authorization2", false},
+		{"a?c", "a/c", true},
+		{"tok[ae]n", "token", true},
+		{"tok[^ae]n", "token", false},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+		{"a.b", "axb", false},
+		{`[\]]x`, "]x", true},
+		{`a[\d]`, "ad", true},
+		{`a[\d]`, "a5", false},
+		{"a[0-9]", "a5", true},
+		{"a[^0-9]", "a5", false},
+		{"a[[]", "a[", true},
+		{"a[.]", "ab", false},
+		{"a[9-0]", "a5", false},
+		{"a[^9-0]", "a5", true},
+		{"caf[é]", "CAFÉ", true},
+	}
+	for _, test := range tests {
+		re, err := compileGlob(test.pattern)
+		if err != nil {
+			t.Errorf("Error compiling %q: %v", test.pattern, err)
+			continue
+		}
+		if match := re.MatchString(test.key); match != test.match {
+			t.Errorf("Expected %q matching %q to be %v", test.pattern,
+				test.key, test.match)
+		}
+	}
+}
+
+func TestRedactBadPattern(t *testing.T) {
+	t.Parallel()
+	root := makeRoot(fakeClock)
+	expectPanic(t, "a bad pattern", func() {
+		root.Redact(Redaction{Keys: []string{"[oops"}})
+	})
+	expectPanic(t, "a trailing backslash", func() {
+		root.Redact(Redaction{Keys: []string{`oops\`}})
+	})
+	for _, pattern := range []string{"[]", "[^]", "[a-]", "[-a]", `[a\`} {
+		expectPanic(t, "a bad class", func() {
+			root.Redact(Redaction{Keys: []string{pattern}})
+		})
+	}
+}
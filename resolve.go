@@ -0,0 +1,75 @@
+package slog
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxLogValueDepth bounds the number of Lazy values, LogValuers, and nested
+// groups that are resolved in turn when resolving a single value.
+var MaxLogValueDepth = 16
+
+var errLogValueDepth = errors.New("slog: exceeded MaxLogValueDepth " +
+	"resolving value")
+
+// Report whether the given value is Lazy, a LogValuer, or a group that contains
+// either.
+func needsResolving(v interface{}, depth int) bool {
+	switch v := v.(type) {
+	case Lazy, LogValuer:
+		return true
+	case Data:
+		if depth >= MaxLogValueDepth {
+			return true
+		}
+		for _, value := range v {
+			if needsResolving(value, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Resolve Lazy values and LogValuers, recursively. Groups that contain values
+// that need resolving are copied rather than modified.
+func resolveValue(v interface{}, depth int) interface{} {
+	for ; depth <= MaxLogValueDepth; depth++ {
+		switch value := v.(type) {
+		case Lazy:
+			v = callResolver(value)
+		case LogValuer:
+			v = callResolver(value.LogValue)
+		case Data:
+			return resolveGroup(value, depth)
+		default:
+			return v
+		}
+	}
+	return errLogValueDepth
+}
+
+func resolveGroup(group Data, depth int) interface{} {
+	if depth >= MaxLogValueDepth {
+		return errLogValueDepth
+	}
+	if !needsResolving(group, depth) {
+		return group
+	}
+	resolved := make(Data, len(group))
+	for k, v := range group {
+		resolved[k] = resolveValue(v, depth+1)
+	}
+	return resolved
+}
+
+// Call the given function, turning panics into errors so that they make it into
+// the log rather than taking down the caller.
+func callResolver(fn func() interface{}) (v interface{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			v = fmt.Errorf("slog: panic resolving value: %v", err)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,125 @@
+package slog
+
+import (
+	"reflect"
+	"testing"
+)
+
+type user struct {
+	id       int
+	password string
+	calls    *int
+}
+
+func (u user) LogValue() interface{} {
+	*u.calls++
+	return Data{"id": u.id, "admin": admin(u.id == 1)}
+}
+
+type admin bool
+
+func (a admin) LogValue() interface{} {
+	return bool(a)
+}
+
+type forever struct{}
+
+func (forever) LogValue() interface{} {
+	return forever{}
+}
+
+type panicky struct{}
+
+func (panicky) LogValue() interface{} {
+	panic("oh no")
+}
+
+func TestLogValuer(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	raw := make(chan map[string]interface{}, 10)
+	root.LogTo(target)
+	sub := root.Bind(nil)
+	sub.SlogTo(raw)
+	sub.SetForward(true)
+
+	calls := 0
+	u := user{1, "hunter2", &calls}
+	sub.Log(Data{"user": u, "group": Data{"user": u}})
+
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" group.user.admin="true" group.user.id="1" user.admin="true" user.id="1"` + "\n",
+	})
+	resolved := Data{"id": 1, "admin": true}
+	expected := map[string]interface{}{
+		"$level": LInfo,
+		"$time":  fakeClock(),
+		"group":  Data{"user": resolved},
+		"user":   resolved,
+	}
+	if line := <-raw; !reflect.DeepEqual(line, expected) {
+		t.Errorf("Expected %#v, but got %#v", expected, line)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+type userError struct {
+	u user
+}
+
+func (e userError) Error() string {
+	return "bad user"
+}
+
+func (e userError) LogFields() map[string]interface{} {
+	return map[string]interface{}{"user": e.u}
+}
+
+func TestLogValuerInError(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	line := Data{"err": userError{user{7, "hunter2", &calls}}}
+
+	expected := `err="bad user" err.user.admin="false" err.user.id="7"` + "\n"
+	if out := Format(line); out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+	expected = `{"err":"bad user","err.user":{"admin":false,"id":7}}` + "\n"
+	if out := FormatJSON(line); out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
+
+func TestResolveValue(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in, out interface{}
+	}{
+		{1, 1},
+		{admin(true), true},
+		{Lazy(func() interface{} { return admin(false) }), false},
+		{forever{}, errLogValueDepth},
+		{Data{"a": Data{"b": forever{}}}, Data{"a": Data{"b": errLogValueDepth}}},
+	}
+	for _, test := range tests {
+		if out := resolveValue(test.in, 0); !reflect.DeepEqual(out, test.out) {
+			t.Errorf("Expected %v to resolve to %v, got %v", test.in,
+				test.out, out)
+		}
+	}
+
+	out := Format(Data{"p": resolveValue(panicky{}, 0)})
+	if expected := `p="slog: panic resolving value: oh no"` + "\n"; out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+
+	group := Data{"a": 1}
+	if out := resolveValue(group, 0); reflect.ValueOf(out).Pointer() !=
+		reflect.ValueOf(group).Pointer() {
+		t.Error("Expected a group without anything to resolve not to be copied")
+	}
+}
@@ -1,5 +1,7 @@
 package slog
 
+import "math"
+
 type rule struct {
 	selector string
 	level    Level
@@ -19,3 +21,15 @@ func (r rules) Less(i, j int) bool {
 func (r rules) Swap(i, j int) {
 	r[i], r[j] = r[j], r[i]
 }
+
+// Return the lowest level of any rule, or the highest possible level if there
+// are no rules.
+func (r rules) minLevel() Level {
+	min := Level(math.MaxInt32)
+	for _, rule := range r {
+		if rule.level < min {
+			min = rule.level
+		}
+	}
+	return min
+}
@@ -26,3 +26,13 @@ func TestRuleSorting(t *testing.T) {
 		}
 	}
 }
+
+func TestRuleMinLevel(t *testing.T) {
+	t.Parallel()
+	if min := testingRules.minLevel(); min != 0 {
+		t.Errorf("Expected minimum level 0, got %d", min)
+	}
+	if min := (rules{}).minLevel(); min <= LError {
+		t.Errorf("Expected no rules to have a high minimum, got %d", min)
+	}
+}
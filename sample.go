@@ -0,0 +1,110 @@
+package slog
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Sampling is a policy that limits the rate at which any single call site can
+produce log lines. In each Period, the First lines logged from a call site are
+let through, and after that only every Thereafter-th line is. If Thereafter is
+zero, all lines after the first First are dropped until the Period elapses. The
+number of lines dropped since a call site last logged is attached to its next
+line as "$sampled_out".
+
+A zero Period is treated as one second. The zero Sampling disables sampling.
+*/
+type Sampling struct {
+	First      int
+	Thereafter int
+	Period     time.Duration
+}
+
+// A samplingRule is a Sampling policy bound to a selector. It holds the state of
+// every call site it has matched so far, so that sampling state is shared by
+// all the Loggers that inherit the rule.
+type samplingRule struct {
+	selector string
+	policy   Sampling
+
+	lock  sync.Mutex
+	sites map[uintptr]*sampler
+}
+
+func newSamplingRule(selector string, policy Sampling) *samplingRule {
+	if policy == (Sampling{}) {
+		return &samplingRule{selector: selector}
+	}
+	if policy.Period == 0 {
+		policy.Period = time.Second
+	}
+	return &samplingRule{
+		selector: selector,
+		policy:   policy,
+		sites:    make(map[uintptr]*sampler),
+	}
+}
+
+// Get the sampler for the given call site, or nil if this rule disables
+// sampling.
+func (sr *samplingRule) sampler(pc uintptr) *sampler {
+	if sr.sites == nil {
+		return nil
+	}
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	s, ok := sr.sites[pc]
+	if !ok {
+		s = &sampler{policy: sr.policy}
+		sr.sites[pc] = s
+	}
+	return s
+}
+
+type samplingRules []*samplingRule
+
+func (r samplingRules) Len() int {
+	return len(r)
+}
+
+func (r samplingRules) Less(i, j int) bool {
+	// We sort largest to smallest, just like rules
+	return r[i].selector > r[j].selector
+}
+
+func (r samplingRules) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+type sampler struct {
+	lock    sync.Mutex
+	policy  Sampling
+	start   time.Time
+	count   int
+	dropped uint64
+}
+
+// Decide whether a line logged at the given time should be let through. If it
+// should, also return the number of lines that were dropped since the last line
+// that was let through.
+func (s *sampler) sample(now time.Time) (bool, uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if now.Sub(s.start) >= s.policy.Period {
+		s.start = now
+		s.count = 0
+	}
+	s.count++
+
+	p := s.policy
+	if s.count <= p.First ||
+		(p.Thereafter > 0 && (s.count-p.First)%p.Thereafter == 0) {
+		dropped := s.dropped
+		s.dropped = 0
+		return true, dropped
+	}
+	s.dropped++
+	return false, 0
+}
@@ -0,0 +1,57 @@
+package slog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	t.Parallel()
+
+	s := newSamplingRule("", Sampling{First: 2, Thereafter: 3}).sampler(1)
+	start := time.Now()
+
+	expected := []struct {
+		ok      bool
+		dropped uint64
+	}{
+		{true, 0}, {true, 0}, {false, 0}, {false, 0}, {true, 2},
+		{false, 0}, {false, 0}, {true, 2}, {false, 0},
+	}
+	for i, e := range expected {
+		ok, dropped := s.sample(start)
+		if ok != e.ok || dropped != e.dropped {
+			t.Errorf("Line %d: expected (%v, %d), got (%v, %d)", i,
+				e.ok, e.dropped, ok, dropped)
+		}
+	}
+
+	// A new period starts afresh, but remembers what it dropped.
+	ok, dropped := s.sample(start.Add(time.Second))
+	if !ok || dropped != 1 {
+		t.Errorf("Expected (true, 1), got (%v, %d)", ok, dropped)
+	}
+}
+
+func TestSamplerDropAll(t *testing.T) {
+	t.Parallel()
+
+	s := newSamplingRule("", Sampling{First: 1, Period: time.Minute}).sampler(1)
+	start := time.Now()
+	for i, e := range []bool{true, false, false, false} {
+		if ok, _ := s.sample(start.Add(time.Duration(i))); ok != e {
+			t.Errorf("Line %d: expected %v, got %v", i, e, ok)
+		}
+	}
+	if ok, dropped := s.sample(start.Add(time.Minute)); !ok || dropped != 3 {
+		t.Errorf("Expected (true, 3), got (%v, %d)", ok, dropped)
+	}
+}
+
+func TestSamplingDisabled(t *testing.T) {
+	t.Parallel()
+
+	if s := newSamplingRule("", Sampling{}).sampler(1); s != nil {
+		t.Errorf("Expected no sampler, got %#v", s)
+	}
+}
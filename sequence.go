@@ -0,0 +1,41 @@
+package slog
+
+import "sync/atomic"
+
+// A SequenceScope determines which lines share a sequence of "$seq" numbers.
+// See Logger.SetSequence.
+type SequenceScope int
+
+const (
+	// Lines are not numbered.
+	NoSequence SequenceScope = iota
+	// Lines are numbered from a single counter shared by the entire
+	// process.
+	ProcessSequence
+	// Lines are numbered from a counter that belongs to the Logger on
+	// which SetSequence was called, and which is shared with its children.
+	LoggerSequence
+)
+
+var processSequence uint64
+
+// A sequence is the counter used to number a Logger's lines. A nil counter
+// means that lines are not numbered.
+type sequence struct {
+	counter *uint64
+}
+
+func newSequence(scope SequenceScope) *sequence {
+	switch scope {
+	case ProcessSequence:
+		return &sequence{counter: &processSequence}
+	case LoggerSequence:
+		return &sequence{counter: new(uint64)}
+	default:
+		return &sequence{}
+	}
+}
+
+func (s *sequence) next() uint64 {
+	return atomic.AddUint64(s.counter, 1)
+}
@@ -0,0 +1,86 @@
+package slog
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSequence(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan map[string]interface{}, 10)
+	root.SlogTo(target)
+
+	a := root.Bind(Data{"a": 1})
+	b := root.Bind(Data{"b": 1})
+	aa := a.Bind(Data{"aa": 1})
+
+	root.Log(Data{})
+	if line := <-target; line["$seq"] != nil {
+		t.Errorf("Expected lines not to be numbered, got %v", line)
+	}
+
+	a.SetSequence(LoggerSequence)
+	b.SetSequence(LoggerSequence)
+	for _, l := range []Logger{a, aa, b, aa, b, root} {
+		l.Log(Data{})
+	}
+	for _, seq := range []interface{}{uint64(1), uint64(2), uint64(1),
+		uint64(3), uint64(2), nil} {
+
+		if line := <-target; line["$seq"] != seq {
+			t.Errorf("Expected $seq %v, got %v", seq, line)
+		}
+	}
+
+	aa.SetSequence(NoSequence)
+	aa.Log(Data{})
+	a.Log(Data{})
+	if line := <-target; line["$seq"] != nil {
+		t.Errorf("Expected lines not to be numbered, got %v", line)
+	}
+	if line := <-target; line["$seq"] != uint64(4) {
+		t.Errorf("Expected $seq 4, got %v", line)
+	}
+}
+
+func TestProcessSequence(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan map[string]interface{}, 100)
+	root.SlogTo(target)
+	root.SetSequence(ProcessSequence)
+
+	// Other tests might be using the process-wide sequence at the same
+	// time, so all we can say is that the numbers are distinct and
+	// increasing for any one goroutine.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(l Logger) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				l.Log(Data{})
+			}
+		}(root.Bind(Data{"g": i}))
+	}
+	wg.Wait()
+	close(target)
+
+	seen := make(map[uint64]bool)
+	last := make(map[interface{}]uint64)
+	for line := range target {
+		seq := line["$seq"].(uint64)
+		if seen[seq] {
+			t.Errorf("Saw $seq %d twice", seq)
+		}
+		seen[seq] = true
+		if seq <= last[line["g"]] {
+			t.Errorf("Expected $seq to increase, got %d after %d",
+				seq, last[line["g"]])
+		}
+		last[line["g"]] = seq
+	}
+}
@@ -4,18 +4,65 @@ modification of log levels on a per-package and per-function basis.
 */
 package slog
 
+import "time"
+
+/*
+Level is the severity of a log line. Higher levels are more severe. In addition
+to the four built-in levels, which are spaced out so that there is room for
+levels in between them, custom levels may be registered with RegisterLevel.
+*/
 type Level int
 
 const (
-	LDebug Level = 1 + iota
+	LDebug Level = 10 * (1 + iota)
 	LInfo
 	LWarn
 	LError
 )
 
-// Data is a convenience type for use in constructing objects for Loggers.
+/*
+Data is a convenience type for use in constructing objects for Loggers.
+
+A value of type Data within a line (or a Logger's context) is a group: a set of
+fields that is namespaced under the group's key, which allows subsystems to use
+the same field names without colliding. Format renders groups by joining the
+keys with periods, as in http.method="GET", while FormatJSON renders them as
+nested objects. Maps of other types are not groups, and are formatted like any
+other value.
+*/
 type Data map[string]interface{}
 
+/*
+Lazy is a value that is expensive to compute, and which should only be computed
+if it is actually going to be logged. If a Lazy is logged (or bound with Bind),
+the function is called at most once per line, and only if the line passes level
+and sampling checks and is dispatched to a target; targets see its result in
+place of the Lazy itself. Lazy values bound to a Logger's context are computed
+afresh for every line, which makes them suitable for values that change over
+time, like the current time.
+*/
+type Lazy func() interface{}
+
+/*
+LogValuer is implemented by types that control how they are logged. When a
+LogValuer is logged, it is replaced with the result of its LogValue method, so
+that, for instance, a User can log only its ID rather than all of its fields
+(some of which might be secret):
+
+	func (u *User) LogValue() interface{} {
+		return u.ID
+	}
+
+LogValue may return another LogValuer or a group (see Data), whose values are
+resolved in turn. Resolution happens at most once per line, at the same time as
+Lazy values are resolved, so formatters and the maps sent to SlogTo targets
+only ever see resolved values. To guard against infinite recursion, values that
+are still unresolved after MaxLogValueDepth steps are replaced with an error.
+*/
+type LogValuer interface {
+	LogValue() interface{}
+}
+
 /*
 Logger is the interface provided by slog's structured logger. It includes
 facilities for logging at several levels, the ability to create "child" loggers
@@ -35,6 +82,46 @@ type Logger interface {
 	// Log at the error level. Returns true if the current function is
 	// configured to log at the error level.
 	Error(lines ...map[string]interface{}) bool
+	// Log at the given level, which may be a custom level registered with
+	// RegisterLevel. Returns true if the current function is configured to
+	// log at that level.
+	LogAt(level Level, lines ...map[string]interface{}) bool
+
+	// Log a single line made up of the given Fields at the debug level.
+	// Otherwise, this behaves just like Debug.
+	DebugFields(fields ...Field) bool
+	// Log a single line made up of the given Fields at the standard (info)
+	// level. Otherwise, this behaves just like Log.
+	LogFields(fields ...Field) bool
+	// Log a single line made up of the given Fields at the warn level.
+	// Otherwise, this behaves just like Warn.
+	WarnFields(fields ...Field) bool
+	// Log a single line made up of the given Fields at the error level.
+	// Otherwise, this behaves just like Error.
+	ErrorFields(fields ...Field) bool
+
+	// Log a line at the debug level whose message, stored in the "$msg"
+	// field, is formatted as with fmt.Sprintf. The message is only
+	// formatted if the line is going to be logged. Otherwise, this behaves
+	// just like Debug.
+	Debugf(format string, args ...interface{}) bool
+	// Log a line with a formatted message at the standard (info) level.
+	// See Debugf for details.
+	Logf(format string, args ...interface{}) bool
+	// Log a line with a formatted message at the warn level. See Debugf
+	// for details.
+	Warnf(format string, args ...interface{}) bool
+	// Log a line with a formatted message at the error level. See Debugf
+	// for details.
+	Errorf(format string, args ...interface{}) bool
+
+	// Returns true if the current function is configured to log at the
+	// given level. This is useful to avoid doing expensive work to build a
+	// line that would not be logged anyway. Unlike the logging methods, it
+	// does not count against the function's sampling policy, so it might
+	// return true even if a line logged immediately afterwards would be
+	// dropped by sampling.
+	Enabled(level Level) bool
 
 	// TODO(carl): Should we add Fatal (log and os.Exit(1)) or Panic (log
 	// and panic a wrapped Error object or something)?
@@ -46,6 +133,13 @@ type Logger interface {
 	// not bind any additional variables.
 	Bind(context map[string]interface{}) Logger
 
+	// Return a new "child" Logger that binds the given variables within
+	// the named group (see Data). Unlike with Bind, variables bound to a
+	// group that is already bound are merged into it, so that children
+	// can add to a group their parents bound, overwriting only variables
+	// with the same name.
+	BindGroup(name string, context map[string]interface{}) Logger
+
 	// Set the log level of a given selector. Selectors are essentially
 	// import paths: "github.com/zenazn/slog" selects this package and all
 	// subpackages, for instance. If you only wish to select the specific
@@ -68,15 +162,122 @@ type Logger interface {
 	// that matches.
 	SetLevel(selector string, level Level)
 
+	// Set the sampling policy of a given selector. Selectors have the same
+	// syntax and are inherited in the same way as for SetLevel, and the
+	// policy a call site is subject to is that of the longest selector
+	// that matches it. Each call site is sampled independently, and its
+	// state is shared by all Loggers that inherit the policy. Calls whose
+	// lines are suppressed by sampling return false. Pass the zero
+	// Sampling to disable sampling for a selector.
+	SetSampling(selector string, sampling Sampling)
+
+	// Collapse identical lines logged through this Logger and its children.
+	// Lines are identical if they are logged at the same level from the
+	// same call site, and their fields (other than system fields like
+	// "$time") are equal. The first of a run of identical lines is logged
+	// immediately, and any others logged within the given window of it
+	// are dropped. Once the window has elapsed, a summary line is logged
+	// that contains the original line's fields, the number of dropped lines
+	// as "$repeated", and the times of the first and last lines as "$first"
+	// and "$last". A window of zero disables deduplication.
+	SetDedup(window time.Duration)
+
+	// Number the lines logged through this Logger and its children with
+	// a "$seq" field, which is assigned atomically when the line is logged.
+	// Since lines are sent to targets concurrently, this allows consumers
+	// of merged streams of lines to put them back in order, and to detect
+	// lines that were lost. The scope determines which lines share a
+	// sequence; pass NoSequence to stop numbering lines. Numbers start at
+	// 1 and are only assigned to lines that pass level and sampling checks.
+	SetSequence(scope SequenceScope)
+
+	// Control how the values of lines logged through this Logger and its
+	// children are captured. Lines are formatted for LogTo targets before
+	// the logging call returns, but the maps sent to SlogTo targets (and
+	// lines held back by SetDedup) share their values with the caller, so
+	// if the caller goes on to modify a map or slice it logged, consumers
+	// might see the modified value or race with the modification. Taking a
+	// snapshot of each line's values when it is logged prevents this, at
+	// some cost. Snapshots are not taken by default.
+	SetSnapshot(mode SnapshotMode)
+
+	// Redact sensitive values from lines logged through this Logger and its
+	// children according to the given policy. Policies accumulate: each
+	// call adds to the policies of this Logger and its parents, all of
+	// which are applied. Redaction happens before lines are sent to any
+	// target, so both formatted and unformatted targets only ever see
+	// redacted lines.
+	Redact(policy Redaction)
+
+	// Control whether lines sent to targets registered on this Logger are
+	// additionally forwarded to the targets that would have received them
+	// had this Logger not registered any targets of its own. By default,
+	// targets registered on a Logger override those of its parents in the
+	// same way that bound variables do: a channel passed to a child
+	// Logger's LogTo with no levels replaces its parent's default target,
+	// for instance. Forwarding is off by default.
+	SetForward(forward bool)
+
+	// Control whether this Logger's lines may be sent to targets
+	// registered on its parents. If propagation is disabled, lines logged
+	// through this Logger or its children are only ever sent to targets
+	// registered on this Logger or its children, and lines that match none
+	// of them are dropped. Level selectors continue to be inherited from
+	// parents regardless. Propagation is on by default.
+	SetPropagate(propagate bool)
+
+	// Add a Hook that is run on every line logged through this Logger and
+	// its children. Hooks are run in order, starting with those of the
+	// root Logger and working down to this one, with the hooks of any
+	// single Logger run in the order they were added; once a hook vetoes
+	// a line, no further hooks are run on it. Hooks added to a parent
+	// apply to its children even if they don't propagate to the parent's
+	// targets (see SetPropagate). Lines vetoed by a hook are not sent to
+	// any target, but do not affect the return value of the logging call.
+	// The returned function removes the hook.
+	AddHook(hook Hook) func()
+
 	// Write log lines for the given levels to the given channel. Logs
 	// written to the channel will be single-line strings without a trailing
 	// newline that are formatted in a manner that's suitable for immediate
 	// printing. As a special case, if no levels are passed, the channel
 	// will be used as a default for levels not otherwise specified.
-	LogTo(chan<- string, ...Level)
+	//
+	// The returned function detaches the channel from this Logger, and
+	// puts back whatever channel (or, for the root Logger's default,
+	// standard output) it replaced. Once it returns, no further lines will
+	// be sent to the channel, and it is safe to close it. Since it waits
+	// for in-flight sends to complete, you must continue reading from the
+	// channel until it returns.
+	LogTo(chan<- string, ...Level) func()
 
 	// Write unformatted log lines for the given level to the given channel.
 	// As a special case, if no levels are passed, the channel will be used
-	// as a default for levels not otherwise specified.
-	SlogTo(chan<- map[string]interface{}, ...Level)
+	// as a default for levels not otherwise specified. The returned
+	// function detaches the channel, as with LogTo.
+	SlogTo(chan<- map[string]interface{}, ...Level) func()
+
+	// Write log lines at or above the given level to the given channel,
+	// formatted as with LogTo. Lines are sent to the target registered for
+	// their exact level if there is one, and otherwise to the target with
+	// the highest threshold that does not exceed their level; lines
+	// matched by neither go to the default target. The returned function
+	// detaches the channel, as with LogTo.
+	LogAtLeast(chan<- string, Level) func()
+
+	// Write unformatted log lines at or above the given level to the given
+	// channel. See LogAtLeast for details.
+	SlogAtLeast(chan<- map[string]interface{}, Level) func()
+
+	// Write log lines that match the given Predicate to the given channel,
+	// formatted as with LogTo. Predicates take precedence over level-based
+	// targets: a line is sent to the channel of the first matching
+	// Predicate, trying this Logger's Predicates in the order they were
+	// registered before those of its parents. The returned function
+	// detaches the channel, as with LogTo.
+	LogWhen(chan<- string, Predicate) func()
+
+	// Write unformatted log lines that match the given Predicate to the
+	// given channel. See LogWhen for details.
+	SlogWhen(chan<- map[string]interface{}, Predicate) func()
 }
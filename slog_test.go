@@ -1,20 +1,24 @@
 package slog
 
 import (
+	"fmt"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
-type fakeTime struct{}
+// The time according to the fake clock, formatted.
+const now = "2014-06-23T12:34:56.789000000Z"
 
-func (_ fakeTime) String() string {
-	return "now"
+func fakeClock() time.Time {
+	return time.Date(2014, 6, 23, 12, 34, 56, 789000000, time.UTC)
 }
 
 func TestBasic(t *testing.T) {
 	t.Parallel()
 
-	root := makeRoot(fakeTime{})
+	root := makeRoot(fakeClock)
 	target := make(chan map[string]interface{}, 1)
 	root.SlogTo(target)
 
@@ -23,7 +27,7 @@ func TestBasic(t *testing.T) {
 	actual := <-target
 	expected := map[string]interface{}{
 		"$level": LInfo,
-		"$time":  fakeTime{},
+		"$time":  fakeClock(),
 		"hello":  "world",
 	}
 	if !reflect.DeepEqual(actual, expected) {
@@ -34,23 +38,54 @@ func TestBasic(t *testing.T) {
 func TestLogTo(t *testing.T) {
 	t.Parallel()
 
-	root := makeRoot(fakeTime{})
+	root := makeRoot(fakeClock)
 	target := make(chan string, 1)
 	root.LogTo(target)
 
 	root.Log(Data{"hello": "world"})
 
 	actual := <-target
-	expected := `$level="INFO" $time="now" hello="world"` + "\n"
+	expected := `$level="INFO" $time="` + now + `" hello="world"` + "\n"
 	if expected != actual {
 		t.Errorf("Expected %q, but got %q", expected, actual)
 	}
 }
 
+func TestCallTime(t *testing.T) {
+	t.Parallel()
+
+	// A clock that advances a second every time it's read, so any read
+	// after the call shows up as a different time.
+	var ticks int64
+	clock := func() time.Time {
+		n := atomic.AddInt64(&ticks, 1)
+		return fakeClock().Add(time.Duration(n) * time.Second)
+	}
+	root := makeRoot(clock)
+	rootT := make(chan string, 10)
+	root.LogTo(rootT)
+	sub := root.Bind(Data{})
+	subT := make(chan map[string]interface{}, 10)
+	sub.SlogTo(subT)
+	sub.SetForward(true)
+
+	for i := 1; i <= 2; i++ {
+		sub.Log(Data{})
+		expected := fakeClock().Add(time.Duration(i) * time.Second)
+		if line := <-subT; line["$time"] != expected {
+			t.Errorf("Expected $time %v, got %v", expected, line)
+		}
+		formatted := expected.Format("2006-01-02T15:04:05.000000000Z07:00")
+		expectLines(t, rootT, []string{
+			`$level="INFO" $time="` + formatted + `"` + "\n",
+		})
+	}
+}
+
 func TestMulti(t *testing.T) {
 	t.Parallel()
 
-	root := makeRoot(fakeTime{})
+	root := makeRoot(fakeClock)
 	target := make(chan string, 100)
 	root.LogTo(target)
 
@@ -60,7 +95,7 @@ func TestMulti(t *testing.T) {
 		}
 	}()
 
-	expected := `$level="INFO" $time="now" hello="world"` + "\n"
+	expected := `$level="INFO" $time="` + now + `" hello="world"` + "\n"
 	for i := 0; i < 100; i++ {
 		actual := <-target
 		if expected != actual {
@@ -81,7 +116,7 @@ func expectLines(t *testing.T, ch <-chan string, lines []string) {
 func TestLevels(t *testing.T) {
 	t.Parallel()
 
-	root := makeRoot(fakeTime{})
+	root := makeRoot(fakeClock)
 	target := make(chan string, 4)
 	root.LogTo(target)
 
@@ -91,16 +126,16 @@ func TestLevels(t *testing.T) {
 	root.Error(Data{})
 
 	expectLines(t, target, []string{
-		`$level="INFO" $time="now"` + "\n",
-		`$level="WARN" $time="now"` + "\n",
-		`$level="ERROR" $time="now"` + "\n",
+		`$level="INFO" $time="` + now + `"` + "\n",
+		`$level="WARN" $time="` + now + `"` + "\n",
+		`$level="ERROR" $time="` + now + `"` + "\n",
 	})
 }
 
 func TestRules(t *testing.T) {
 	t.Parallel()
 
-	root := makeRoot(fakeTime{})
+	root := makeRoot(fakeClock)
 	target := make(chan string, 4)
 	root.LogTo(target)
 	root.SetLevel("example.com/not/a/real/thing", LError)
@@ -112,8 +147,8 @@ func TestRules(t *testing.T) {
 	root.Error(Data{})
 
 	expectLines(t, target, []string{
-		`$level="WARN" $time="now"` + "\n",
-		`$level="ERROR" $time="now"` + "\n",
+		`$level="WARN" $time="` + now + `"` + "\n",
+		`$level="ERROR" $time="` + now + `"` + "\n",
 	})
 
 	root.SetLevel("github.com/zenazn/slog.", LDebug)
@@ -124,17 +159,17 @@ func TestRules(t *testing.T) {
 	root.Error(Data{})
 
 	expectLines(t, target, []string{
-		`$level="DEBUG" $time="now"` + "\n",
-		`$level="INFO" $time="now"` + "\n",
-		`$level="WARN" $time="now"` + "\n",
-		`$level="ERROR" $time="now"` + "\n",
+		`$level="DEBUG" $time="` + now + `"` + "\n",
+		`$level="INFO" $time="` + now + `"` + "\n",
+		`$level="WARN" $time="` + now + `"` + "\n",
+		`$level="ERROR" $time="` + now + `"` + "\n",
 	})
 }
 
 func TestBasicBind(t *testing.T) {
 	t.Parallel()
 
-	root := makeRoot(fakeTime{})
+	root := makeRoot(fakeClock)
 	target := make(chan string, 2)
 	root.LogTo(target)
 
@@ -143,15 +178,15 @@ func TestBasicBind(t *testing.T) {
 	sub.Log(Data{"hello": "universe", "color": "red"})
 
 	expectLines(t, target, []string{
-		`$level="INFO" $time="now" foo="bar" hello="world"` + "\n",
-		`$level="INFO" $time="now" color="red" hello="universe"` + "\n",
+		`$level="INFO" $time="` + now + `" foo="bar" hello="world"` + "\n",
+		`$level="INFO" $time="` + now + `" color="red" hello="universe"` + "\n",
 	})
 }
 
 func TestBind(t *testing.T) {
 	t.Parallel()
 
-	root := makeRoot(fakeTime{})
+	root := makeRoot(fakeClock)
 	target := make(chan string, 7)
 	target2 := make(chan string, 3)
 	root.LogTo(target)
@@ -179,7 +214,7 @@ func TestBind(t *testing.T) {
 	subsub.Log(Data{"space": "ship"})
 	sub.Log(Data{"space": "ship"})
 
-	prefix := `$level="INFO" $time="now" `
+	prefix := `$level="INFO" $time="` + now + `" `
 	expectLines(t, target, []string{
 		prefix + `space="ship"` + "\n",
 		prefix + `hello="world" space="ship"` + "\n",
@@ -195,3 +230,749 @@ func TestBind(t *testing.T) {
 		prefix + `hello="world" space="ship"` + "\n",
 	})
 }
+
+func TestCancel(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	base := make(chan string, 3)
+	target := make(chan string, 3)
+	target2 := make(chan string, 3)
+	root.LogTo(base)
+	cancelRoot := root.LogTo(target)
+
+	sub := root.Bind(Data{"hello": "world"})
+	cancel := sub.LogTo(target2, LInfo)
+	cancelWarn := sub.LogTo(target2, LWarn)
+
+	sub.Log(Data{})
+	sub.Warn(Data{})
+	cancel()
+	cancel()
+	sub.Log(Data{})
+	sub.Warn(Data{})
+	cancelWarn()
+	sub.Warn(Data{})
+	close(target2)
+
+	// Canceling a target puts back the one it replaced.
+	cancelRoot()
+	sub.Error(Data{})
+	close(target)
+
+	expectLines(t, target2, []string{
+		`$level="INFO" $time="` + now + `" hello="world"` + "\n",
+		`$level="WARN" $time="` + now + `" hello="world"` + "\n",
+		`$level="WARN" $time="` + now + `" hello="world"` + "\n",
+	})
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" hello="world"` + "\n",
+		`$level="WARN" $time="` + now + `" hello="world"` + "\n",
+	})
+	expectLines(t, base, []string{
+		`$level="ERROR" $time="` + now + `" hello="world"` + "\n",
+	})
+}
+
+func TestCancelRestores(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	stdout := root.getTCache().defaultTarget
+	a := make(chan string, 4)
+	b := make(chan string, 4)
+	c := make(chan string, 4)
+
+	root.LogTo(a, LWarn)
+	cancelB := root.LogTo(b, LWarn)
+	cancelC := root.LogTo(c, LWarn)
+	root.Warn(Data{"n": 1})
+	// Canceling a target that has itself been replaced forgets it.
+	cancelB()
+	root.Warn(Data{"n": 2})
+	cancelC()
+	root.Warn(Data{"n": 3})
+
+	warn := `$level="WARN" $time="` + now + `"`
+	expectLines(t, a, []string{warn + ` n="3"` + "\n"})
+	expectLines(t, c, []string{
+		warn + ` n="1"` + "\n",
+		warn + ` n="2"` + "\n",
+	})
+	select {
+	case line := <-b:
+		t.Errorf("Unexpected line %q", line)
+	default:
+	}
+
+	cancel := root.LogTo(a)
+	if root.getTCache().defaultTarget == stdout {
+		t.Error("Expected LogTo to replace the default target")
+	}
+	cancel()
+	if root.getTCache().defaultTarget != stdout {
+		t.Error("Expected canceling LogTo to restore stdout")
+	}
+}
+
+func TestCancelConcurrent(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string)
+	cancel := root.LogTo(target)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			root.Log(Data{"i": i})
+		}
+	}()
+
+	<-target
+	canceled := make(chan struct{})
+	go func() {
+		cancel()
+		close(canceled)
+	}()
+	for {
+		select {
+		case <-target:
+			continue
+		case <-canceled:
+		}
+		break
+	}
+	// If anyone were to send after cancel returned, this would panic.
+	close(target)
+	<-done
+}
+
+func TestAtLeast(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	root.SetLevel("github.com/zenazn/slog", LDebug)
+	target := make(chan string, 4)
+	pager := make(chan string, 4)
+	errors := make(chan string, 4)
+	root.LogTo(target)
+	root.LogAtLeast(pager, LWarn)
+
+	sub := root.Bind(Data{"hello": "world"})
+	cancel := sub.LogTo(errors, LError)
+
+	sub.Debug(Data{})
+	sub.Log(Data{})
+	sub.Warn(Data{})
+	sub.Error(Data{})
+	cancel()
+	sub.Error(Data{})
+
+	prefix := `$time="` + now + `" hello="world"` + "\n"
+	expectLines(t, target, []string{
+		`$level="DEBUG" ` + prefix,
+		`$level="INFO" ` + prefix,
+	})
+	expectLines(t, pager, []string{
+		`$level="WARN" ` + prefix,
+		`$level="ERROR" ` + prefix,
+	})
+	expectLines(t, errors, []string{
+		`$level="ERROR" ` + prefix,
+	})
+}
+
+func TestWhen(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 4)
+	audit := make(chan string, 4)
+	errors := make(chan string, 4)
+	root.LogTo(target)
+	root.LogTo(target, LError)
+	root.LogWhen(errors, HasKey("err"))
+
+	billing := root.Bind(Data{"component": "billing"})
+	cancel := billing.LogWhen(audit, Equals("component", "billing"))
+
+	root.Log(Data{"a": 1})
+	root.Error(Data{"err": "oops"})
+	billing.Log(Data{"a": 2})
+	billing.Error(Data{"err": "oops"})
+	cancel()
+	billing.Log(Data{"a": 3})
+
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" a="1"` + "\n",
+		`$level="INFO" $time="` + now + `" a="3" component="billing"` + "\n",
+	})
+	expectLines(t, errors, []string{
+		`$level="ERROR" $time="` + now + `" err="oops"` + "\n",
+	})
+	expectLines(t, audit, []string{
+		`$level="INFO" $time="` + now + `" a="2" component="billing"` + "\n",
+		`$level="ERROR" $time="` + now + `" component="billing" err="oops"` + "\n",
+	})
+}
+
+func TestDefaultOverride(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	rootT := make(chan string, 10)
+	rootErrT := make(chan string, 10)
+	subT := make(chan string, 10)
+	subsubT := make(chan string, 10)
+	root.LogTo(rootT)
+	root.LogTo(rootErrT, LError)
+
+	sub := root.Bind(Data{"sub": 1})
+	subsub := sub.Bind(Data{"subsub": 1})
+	sibling := root.Bind(Data{"sibling": 1})
+	sub.LogTo(subT)
+
+	root.Log(Data{})
+	sub.Log(Data{})
+	subsub.Log(Data{})
+	sibling.Log(Data{})
+	subsub.Error(Data{})
+
+	cancel := subsub.LogTo(subsubT)
+	sub.Log(Data{})
+	subsub.Log(Data{})
+	cancel()
+	subsub.Log(Data{})
+
+	prefix := `$level="INFO" $time="` + now + `"`
+	expectLines(t, rootT, []string{
+		prefix + "\n",
+		prefix + ` sibling="1"` + "\n",
+	})
+	expectLines(t, rootErrT, []string{
+		`$level="ERROR" $time="` + now + `" sub="1" subsub="1"` + "\n",
+	})
+	expectLines(t, subT, []string{
+		prefix + ` sub="1"` + "\n",
+		prefix + ` sub="1" subsub="1"` + "\n",
+		prefix + ` sub="1"` + "\n",
+		prefix + ` sub="1" subsub="1"` + "\n",
+	})
+	expectLines(t, subsubT, []string{
+		prefix + ` sub="1" subsub="1"` + "\n",
+	})
+}
+
+func TestForward(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	rootT := make(chan string, 10)
+	subT := make(chan string, 10)
+	subsubT := make(chan string, 10)
+	root.LogTo(rootT)
+
+	sub := root.Bind(Data{"sub": 1})
+	subsub := sub.Bind(Data{"subsub": 1})
+	sub.LogTo(subT)
+	subsub.LogTo(subsubT, LWarn)
+	sub.SetForward(true)
+
+	sub.Log(Data{})
+	subsub.Log(Data{})
+	subsub.Warn(Data{})
+
+	subsub.SetForward(true)
+	subsub.Warn(Data{})
+
+	sub.SetForward(false)
+	sub.Log(Data{})
+	subsub.Warn(Data{})
+
+	info := `$level="INFO" $time="` + now + `"`
+	warn := `$level="WARN" $time="` + now + `"`
+	expectLines(t, rootT, []string{
+		info + ` sub="1"` + "\n",
+		info + ` sub="1" subsub="1"` + "\n",
+		warn + ` sub="1" subsub="1"` + "\n",
+	})
+	expectLines(t, subT, []string{
+		info + ` sub="1"` + "\n",
+		info + ` sub="1" subsub="1"` + "\n",
+		warn + ` sub="1" subsub="1"` + "\n",
+		info + ` sub="1"` + "\n",
+		warn + ` sub="1" subsub="1"` + "\n",
+	})
+	expectLines(t, subsubT, []string{
+		warn + ` sub="1" subsub="1"` + "\n",
+		warn + ` sub="1" subsub="1"` + "\n",
+		warn + ` sub="1" subsub="1"` + "\n",
+	})
+	select {
+	case line := <-rootT:
+		t.Errorf("Unexpected line %q", line)
+	default:
+	}
+}
+
+func TestForwardSlogTo(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	rootT := make(chan map[string]interface{}, 10)
+	subT := make(chan map[string]interface{}, 10)
+	root.SlogTo(rootT)
+	sub := root.Bind(Data{"sub": 1})
+	sub.SlogTo(subT)
+	sub.SetForward(true)
+
+	sub.Log(Data{"a": 1})
+	subLine, rootLine := <-subT, <-rootT
+	subLine["a"] = 2
+	if rootLine["a"] != 1 {
+		t.Errorf("Expected each target to get its own line, got %v",
+			rootLine)
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	root.SetLevel("github.com/zenazn/slog", LWarn)
+	rootT := make(chan string, 10)
+	subT := make(chan string, 10)
+	root.LogTo(rootT)
+	root.LogWhen(rootT, HasKey("err"))
+
+	sub := root.Bind(Data{"sub": 1})
+	subsub := sub.Bind(Data{"subsub": 1})
+	sub.SetPropagate(false)
+	sub.SetForward(true)
+
+	// Nothing to route to yet, so these are dropped.
+	sub.Warn(Data{"err": "oops"})
+	subsub.Warn(Data{})
+
+	sub.LogTo(subT, LError)
+	sub.Log(Data{})
+	sub.Warn(Data{})
+	subsub.Error(Data{"err": "oops"})
+
+	sub.SetPropagate(true)
+	sub.Warn(Data{})
+	subsub.Error(Data{})
+
+	warn := `$level="WARN" $time="` + now + `"`
+	err := `$level="ERROR" $time="` + now + `"`
+	expectLines(t, subT, []string{
+		err + ` err="oops" sub="1" subsub="1"` + "\n",
+		err + ` sub="1" subsub="1"` + "\n",
+	})
+	expectLines(t, rootT, []string{
+		warn + ` sub="1"` + "\n",
+		err + ` sub="1" subsub="1"` + "\n",
+	})
+	select {
+	case line := <-rootT:
+		t.Errorf("Unexpected line %q", line)
+	default:
+	}
+}
+
+func TestSampling(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 20)
+	root.LogTo(target)
+	root.SetSampling("example.com/not/a/real/thing", Sampling{First: 1})
+
+	sub := root.Bind(nil)
+	sub.SetSampling("github.com/zenazn/slog.TestSampling", Sampling{
+		First:      2,
+		Thereafter: 3,
+		Period:     time.Hour,
+	})
+
+	var logged []bool
+	for i := 0; i < 8; i++ {
+		logged = append(logged, sub.Log(Data{"i": i}))
+		root.Log(Data{"root": i})
+	}
+
+	expected := []bool{true, true, false, false, true, false, false, true}
+	if !reflect.DeepEqual(logged, expected) {
+		t.Errorf("Expected %v, got %v", expected, logged)
+	}
+
+	var lines []string
+	for i := 0; i < 8; i++ {
+		switch i {
+		case 0, 1:
+			lines = append(lines, fmt.Sprintf(
+				`$level="INFO" $time=%q i="%d"`+"\n", now, i))
+		case 4, 7:
+			lines = append(lines, fmt.Sprintf(
+				`$level="INFO" $sampled_out="2" $time=%q i="%d"`+"\n",
+				now, i))
+		}
+		lines = append(lines, fmt.Sprintf(
+			`$level="INFO" $time=%q root="%d"`+"\n", now, i))
+	}
+	expectLines(t, target, lines)
+}
+
+// Log from a different function than the test itself.
+func logDebugElsewhere(l Logger) bool {
+	return l.Debug(Data{})
+}
+
+func TestCallSites(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	root.LogTo(target)
+	root.SetLevel("github.com/zenazn/slog.TestCallSites", LDebug)
+
+	// Both directly on the concrete type (where Debug is likely to be
+	// inlined) and through the interface.
+	var iface Logger = root
+	if !root.Debug(Data{}) || !iface.Debug(Data{}) {
+		t.Error("Expected debug lines to be logged from TestCallSites")
+	}
+	if logDebugElsewhere(root) || logDebugElsewhere(iface) {
+		t.Error("Expected debug lines not to be logged from elsewhere")
+	}
+	func() {
+		if !root.Debug(Data{}) {
+			t.Error("Expected debug lines to be logged from a closure")
+		}
+	}()
+
+	// Even after the call sites have been cached.
+	for i := 0; i < 2; i++ {
+		if !root.Debug(Data{}) || logDebugElsewhere(root) {
+			t.Error("Unexpected result from cached call site")
+		}
+	}
+}
+
+func enabledElsewhere(l Logger, level Level) bool {
+	return l.Enabled(level)
+}
+
+func TestEnabled(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	root.LogTo(target)
+	sub := root.Bind(Data{"hello": "world"})
+	root.SetLevel("github.com/zenazn/slog.TestEnabled", LDebug)
+	sub.SetSampling("github.com/zenazn/slog.TestEnabled", Sampling{
+		First:  1,
+		Period: time.Hour,
+	})
+
+	var iface Logger = sub
+	if !root.Enabled(LDebug) || !iface.Enabled(LDebug) {
+		t.Error("Expected debug to be enabled in TestEnabled")
+	}
+	if enabledElsewhere(root, LDebug) || enabledElsewhere(iface, LDebug) {
+		t.Error("Expected debug to be disabled elsewhere")
+	}
+	if !enabledElsewhere(iface, LInfo) {
+		t.Error("Expected info to be enabled elsewhere")
+	}
+
+	// Enabled doesn't count against sampling.
+	for i := 0; i < 3; i++ {
+		if !sub.Enabled(LInfo) {
+			t.Error("Expected info to be enabled")
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if logged := sub.Log(Data{}); logged != (i == 0) {
+			t.Errorf("Line %d: expected %v, got %v", i, i == 0,
+				logged)
+		}
+	}
+}
+
+func TestLazy(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	raw := make(chan map[string]interface{}, 10)
+	root.LogTo(target)
+
+	calls := 0
+	expensive := Lazy(func() interface{} {
+		calls++
+		return calls
+	})
+
+	sub := root.Bind(Data{"bound": expensive})
+	sub.SlogTo(raw, LWarn)
+	sub.SetForward(true)
+
+	sub.Debug(Data{"line": expensive})
+	if calls != 0 {
+		t.Errorf("Expected no calls for a disabled line, got %d", calls)
+	}
+
+	root.Log(Data{"line": expensive})
+	sub.Log(Data{})
+	sub.Warn(Data{})
+
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" line="1"` + "\n",
+		`$level="INFO" $time="` + now + `" bound="2"` + "\n",
+		`$level="WARN" $time="` + now + `" bound="3"` + "\n",
+	})
+	if line := <-raw; line["bound"] != 3 {
+		t.Errorf("Expected the resolved value 3, got %v", line["bound"])
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	raw := make(chan map[string]interface{}, 10)
+	root.LogTo(target)
+	root.SlogTo(raw, LWarn)
+	sub := root.Bind(Data{"hello": "world", "n": 0})
+
+	sub.DebugFields(String("dropped", "!"))
+	sub.LogFields(Int("n", 1), String("b", "x"), Bool("a", true))
+	sub.WarnFields(Int("n", 2), Int("n", 3), Dur("d", time.Second))
+
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" a="true" b="x" hello="world" n="1"` + "\n",
+	})
+	expected := map[string]interface{}{
+		"$level": LWarn,
+		"$time":  fakeClock(),
+		"d":      time.Second,
+		"hello":  "world",
+		"n":      3,
+	}
+	if line := <-raw; !reflect.DeepEqual(line, expected) {
+		t.Errorf("Expected %#v, but got %#v", expected, line)
+	}
+}
+
+func TestBindGroup(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	raw := make(chan map[string]interface{}, 10)
+	root.LogTo(target)
+	root.SlogTo(raw, LWarn)
+
+	sub := root.Bind(Data{"id": 1}).BindGroup("http", Data{
+		"method": "GET",
+		"req":    Data{"id": 2},
+	})
+	subsub := sub.BindGroup("http", Data{"path": "/", "req": Data{"n": 3}})
+
+	sub.Log(Data{"id": 4})
+	subsub.LogFields(Group("db", Data{"id": 5}))
+	subsub.Log(Data{"http": Data{"status": 200}})
+	sub.Warn(Data{})
+
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" http.method="GET" http.req.id="2" id="4"` + "\n",
+		`$level="INFO" $time="` + now + `" db.id="5" http.method="GET" http.path="/" http.req.id="2" http.req.n="3" id="1"` + "\n",
+		`$level="INFO" $time="` + now + `" http.status="200" id="1"` + "\n",
+	})
+	expected := map[string]interface{}{
+		"$level": LWarn,
+		"$time":  fakeClock(),
+		"http":   Data{"method": "GET", "req": Data{"id": 2}},
+		"id":     1,
+	}
+	if line := <-raw; !reflect.DeepEqual(line, expected) {
+		t.Errorf("Expected %#v, but got %#v", expected, line)
+	}
+}
+
+type countingStringer struct {
+	calls *int
+}
+
+func (c countingStringer) String() string {
+	*c.calls++
+	return "counted"
+}
+
+func TestLogf(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	root.LogTo(target)
+	root.SetSequence(LoggerSequence)
+	sub := root.Bind(Data{"a": "b"})
+
+	calls := 0
+	arg := countingStringer{&calls}
+	if sub.Debugf("%v", arg) {
+		t.Error("Expected Debugf to be disabled")
+	}
+	if calls != 0 {
+		t.Errorf("Expected a disabled line not to be formatted")
+	}
+
+	sub.Logf("hello %s %d", arg, 42)
+	sub.Errorf("oh no")
+
+	expectLines(t, target, []string{
+		`$level="INFO" $seq="1" $time="` + now + `" $msg="hello counted 42" a="b"` + "\n",
+		`$level="ERROR" $seq="2" $time="` + now + `" $msg="oh no" a="b"` + "\n",
+	})
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+// This test modifies a global, so it can't run in parallel.
+func TestPreserveFieldOrder(t *testing.T) {
+	PreserveFieldOrder = true
+	defer func() {
+		PreserveFieldOrder = false
+	}()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	root.LogTo(target)
+	sub := root.Bind(Data{"hello": "world", "n": 0})
+
+	sub.LogFields(Int("n", 1), String("b", "x"), Bool("a", true), Int("n", 2))
+	sub.Log(Data{"n": 1, "b": "x", "a": true})
+	// Redaction works on the merged map, which shouldn't lose the order.
+	sub.Redact(Redaction{Keys: []string{"b"}})
+	sub.LogFields(Int("n", 1), String("b", "x"))
+	// Nor should it bring back fields that a hook deleted.
+	sub.AddHook(func(line map[string]interface{}) bool {
+		delete(line, "secret")
+		return true
+	})
+	sub.LogFields(String("a", "x"), String("secret", "pw"))
+
+	expectLines(t, target, []string{
+		`$level="INFO" $time="` + now + `" hello="world" n="2" b="x" a="true"` + "\n",
+		`$level="INFO" $time="` + now + `" a="true" b="x" hello="world" n="1"` + "\n",
+		`$level="INFO" $time="` + now + `" hello="world" n="1" b="[REDACTED]"` + "\n",
+		`$level="INFO" $time="` + now + `" hello="world" n="0" a="x"` + "\n",
+	})
+}
+
+// Make a root logger whose lines are formatted and then discarded.
+func benchRoot(b *testing.B) (*logger, func()) {
+	root := makeRoot(fakeClock)
+	target := make(chan string, 100)
+	done := make(chan struct{})
+	go func() {
+		for range target {
+		}
+		close(done)
+	}()
+	cancel := root.LogTo(target)
+	return root, func() {
+		cancel()
+		close(target)
+		<-done
+	}
+}
+
+var benchLine = Data{"hello": "world", "n": 42, "ok": true}
+
+func BenchmarkDisabled(b *testing.B) {
+	root, done := benchRoot(b)
+	defer done()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.Debug(benchLine)
+	}
+}
+
+func BenchmarkEnabled(b *testing.B) {
+	root, done := benchRoot(b)
+	defer done()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.Log(benchLine)
+	}
+}
+
+func BenchmarkBound(b *testing.B) {
+	root, done := benchRoot(b)
+	defer done()
+	sub := root.Bind(Data{"request": "abc123", "user": 17})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sub.Log(benchLine)
+	}
+}
+
+func BenchmarkRoute(b *testing.B) {
+	root, done := benchRoot(b)
+	defer done()
+	errors := make(chan string, 1)
+	root.LogWhen(errors, HasKey("err"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.Log(benchLine)
+	}
+}
+
+func BenchmarkDisabledCached(b *testing.B) {
+	root, done := benchRoot(b)
+	defer done()
+	// Since some rule could enable debug logs, we have to check the call
+	// site.
+	root.SetLevel("example.com/not/a/real/thing", LDebug)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.Debug(benchLine)
+	}
+}
+
+func BenchmarkEnabledCached(b *testing.B) {
+	root, done := benchRoot(b)
+	defer done()
+	root.SetLevel("example.com/not/a/real/thing", LDebug)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.Log(benchLine)
+	}
+}
+
+func BenchmarkFields(b *testing.B) {
+	root, done := benchRoot(b)
+	defer done()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.LogFields(String("hello", "world"), Int("n", 42),
+			Bool("ok", true))
+	}
+}
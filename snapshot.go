@@ -0,0 +1,123 @@
+package slog
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// A SnapshotMode determines how the values of a line are captured when it is
+// logged. See Logger.SetSnapshot.
+type SnapshotMode int
+
+const (
+	// Values are passed along as they are.
+	NoSnapshot SnapshotMode = iota
+	// Maps, slices, and arrays are deep-copied. Other values, including
+	// pointers and structs (even those containing maps or slices), are
+	// passed along as they are.
+	CopySnapshot
+	// Values are formatted as strings, as with Format, unless they are
+	// strings, numbers, booleans, Levels, time.Times, time.Durations,
+	// errors, or groups (whose values are formatted in turn).
+	FormatSnapshot
+)
+
+// Snapshot the values of the given record according to the given mode.
+func (mode SnapshotMode) snapshot(r *record) {
+	m := r.asMap()
+	for k, v := range m {
+		if mode == CopySnapshot {
+			m[k] = copyValue(v, 0)
+		} else {
+			m[k] = formatValue(v, 0)
+		}
+	}
+}
+
+// Report whether the given value is immutable, or close enough that we don't
+// need to worry about it. Errors aren't, necessarily, but they'd be useless if
+// we formatted them.
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8,
+		uint16, uint32, uint64, uintptr, float32, float64, complex64,
+		complex128, Level, time.Time, time.Duration, error:
+		return true
+	}
+	return false
+}
+
+func copyValue(v interface{}, depth int) interface{} {
+	if isScalar(v) {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return deepCopy(rv, depth).Interface()
+	}
+	return v
+}
+
+func deepCopy(rv reflect.Value, depth int) reflect.Value {
+	if depth >= MaxLogValueDepth {
+		return rv
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return rv
+		}
+		m := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), deepCopy(iter.Value(), depth+1))
+		}
+		return m
+	case reflect.Slice:
+		if rv.IsNil() {
+			return rv
+		}
+		s := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		copyElems(s, rv, depth)
+		return s
+	case reflect.Array:
+		a := reflect.New(rv.Type()).Elem()
+		copyElems(a, rv, depth)
+		return a
+	case reflect.Interface:
+		if rv.IsNil() {
+			return rv
+		}
+		i := reflect.New(rv.Type()).Elem()
+		i.Set(deepCopy(rv.Elem(), depth+1))
+		return i
+	}
+	return rv
+}
+
+func copyElems(dst, src reflect.Value, depth int) {
+	switch src.Type().Elem().Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deepCopy(src.Index(i), depth+1))
+		}
+	default:
+		reflect.Copy(dst, src)
+	}
+}
+
+func formatValue(v interface{}, depth int) interface{} {
+	if isScalar(v) {
+		return v
+	}
+	if group, ok := v.(Data); ok && depth < MaxLogValueDepth {
+		formatted := make(Data, len(group))
+		for k, inner := range group {
+			formatted[k] = formatValue(inner, depth+1)
+		}
+		return formatted
+	}
+	return fmt.Sprintf("%+v", v)
+}
@@ -0,0 +1,91 @@
+package slog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	raw := make(chan map[string]interface{}, 10)
+	root.SlogTo(raw)
+	copied := root.Bind(nil)
+	copied.SetSnapshot(CopySnapshot)
+	formatted := copied.Bind(nil)
+	formatted.SetSnapshot(FormatSnapshot)
+	off := formatted.Bind(nil)
+	off.SetSnapshot(NoSnapshot)
+
+	tags := []string{"a", "b"}
+	counts := map[string][]int{"x": {1, 2}}
+	line := func() Data {
+		return Data{"tags": tags, "counts": counts, "n": 1,
+			"group": Data{"tags": tags}, "d": time.Second}
+	}
+	copied.Log(line())
+	formatted.Log(line())
+	off.Log(line())
+	tags[0] = "changed"
+	counts["x"][0] = 100
+
+	expected := []map[string]interface{}{
+		{
+			"tags":   []string{"a", "b"},
+			"counts": map[string][]int{"x": {1, 2}},
+			"group":  Data{"tags": []string{"a", "b"}},
+		},
+		{
+			"tags":   "[a b]",
+			"counts": "map[x:[1 2]]",
+			"group":  Data{"tags": "[a b]"},
+		},
+		{
+			"tags":   []string{"changed", "b"},
+			"counts": map[string][]int{"x": {100, 2}},
+			"group":  Data{"tags": []string{"changed", "b"}},
+		},
+	}
+	for _, e := range expected {
+		e["$level"] = LInfo
+		e["$time"] = fakeClock()
+		e["n"] = 1
+		e["d"] = time.Second
+		if line := <-raw; !reflect.DeepEqual(line, e) {
+			t.Errorf("Expected %#v, but got %#v", e, line)
+		}
+	}
+}
+
+func TestCopyValue(t *testing.T) {
+	t.Parallel()
+	inner := []int{1}
+	tests := []interface{}{
+		[]interface{}{inner, map[int]string{1: "a"}, nil},
+		[2][]int{inner, nil},
+		map[string]interface{}{"a": inner},
+		[]byte(nil),
+	}
+	copies := make([]interface{}, len(tests))
+	for i, test := range tests {
+		copies[i] = copyValue(test, 0)
+		if !reflect.DeepEqual(copies[i], test) {
+			t.Errorf("Expected a copy of %#v, got %#v", test, copies[i])
+		}
+	}
+
+	inner[0] = 2
+	nested := []int{
+		copies[0].([]interface{})[0].([]int)[0],
+		copies[1].([2][]int)[0][0],
+		copies[2].(map[string]interface{})["a"].([]int)[0],
+	}
+	for i, n := range nested {
+		if n != 1 {
+			t.Errorf("Expected %#v to be copied deeply, got %#v",
+				tests[i], copies[i])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package slog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/*
+System fields are the fields slog itself adds to lines, all of which have keys
+that begin with "$". The built-in system fields are:
+
+	$level        the Level the line was logged at
+	$time         when the line was logged
+	$msg          the message of lines logged with methods like Logf
+	$seq          the line's sequence number (see Logger.SetSequence)
+	$sampled_out  the number of lines dropped by sampling (see Logger.SetSampling)
+	$repeated     the number of duplicate lines dropped (see Logger.SetDedup)
+	$first        when the first of a run of duplicate lines was logged
+	$last         when the last of a run of duplicate lines was logged
+	$omitted      the number of fields omitted from a formatted line (see MaxFields)
+
+To prevent lines from masquerading as something they're not, keys that clash
+with a system field are renamed by prefixing them with ReservedPrefix when they
+are logged or bound, so that logging Data{"$level": "lol"} produces a line with
+both "$level" and "_$level" fields. Other keys that begin with "$" are left
+alone, but may be claimed by future versions of slog. Packages that add their
+own system fields should register them with RegisterSystemField.
+*/
+const ReservedPrefix = "_"
+
+var (
+	systemFieldsLock sync.Mutex
+	systemFields     = &map[string]bool{
+		"$level":       true,
+		"$time":        true,
+		"$msg":         true,
+		"$seq":         true,
+		"$sampled_out": true,
+		"$repeated":    true,
+		"$first":       true,
+		"$last":        true,
+		"$omitted":     true,
+	}
+)
+
+// RegisterSystemField reserves the given key, which must begin with "$", as a
+// system field. See ReservedPrefix for details.
+func RegisterSystemField(key string) {
+	if !strings.HasPrefix(key, "$") {
+		panic(fmt.Sprintf("slog: system field %q does not begin with $",
+			key))
+	}
+
+	systemFieldsLock.Lock()
+	defer systemFieldsLock.Unlock()
+	old := atomicGetSystemFields()
+	if old[key] {
+		return
+	}
+	fields := make(map[string]bool, len(old)+1)
+	for k := range old {
+		fields[k] = true
+	}
+	fields[key] = true
+	atomicSetSystemFields(&fields)
+}
+
+func reserved(key string) bool {
+	return len(key) > 0 && key[0] == '$' && atomicGetSystemFields()[key]
+}
+
+// Return the key that a user-supplied key should be logged as.
+func protectKey(key string) string {
+	if reserved(key) {
+		return ReservedPrefix + key
+	}
+	return key
+}
+
+// Return the given line, or a copy of it with any reserved keys renamed.
+func protectLine(line map[string]interface{}) map[string]interface{} {
+	for k := range line {
+		if reserved(k) {
+			return renameLine(line)
+		}
+	}
+	return line
+}
+
+func renameLine(line map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(line))
+	for k, v := range line {
+		m[protectKey(k)] = v
+	}
+	return m
+}
+
+// Return the given Fields, or a copy of them with any reserved keys renamed.
+func protectFields(fields []Field) []Field {
+	for i, f := range fields {
+		if reserved(f.Key) {
+			fs := make([]Field, len(fields))
+			copy(fs, fields)
+			for j := i; j < len(fs); j++ {
+				fs[j].Key = protectKey(fs[j].Key)
+			}
+			return fs
+		}
+	}
+	return fields
+}
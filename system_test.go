@@ -0,0 +1,59 @@
+package slog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func init() {
+	RegisterSystemField("$test_host")
+}
+
+func TestReservedKeys(t *testing.T) {
+	t.Parallel()
+
+	root := makeRoot(fakeClock)
+	target := make(chan string, 10)
+	raw := make(chan map[string]interface{}, 10)
+	root.LogTo(target)
+	root.SlogTo(raw, LWarn)
+
+	sub := root.Bind(Data{"$time": "bound", "$other": 1}).
+		BindGroup("$msg", Data{"$level": "nested"})
+
+	line := Data{"$level": "lol", "$test_host": "example.com"}
+	sub.Log(line)
+	sub.LogFields(String("$seq", "x"), String("a", "b"), Int("$seq", 2))
+	sub.Warn(Data{})
+
+	expectLines(t, target, []string{
+		`$level="INFO" $other="1" $time="` + now + `" _$level="lol" _$msg.$level="nested" _$test_host="example.com" _$time="bound"` + "\n",
+		`$level="INFO" $other="1" $time="` + now + `" _$msg.$level="nested" _$seq="2" _$time="bound" a="b"` + "\n",
+	})
+	expected := map[string]interface{}{
+		"$level": LWarn,
+		"$time":  fakeClock(),
+		"$other": 1,
+		"_$msg":  Data{"$level": "nested"},
+		"_$time": "bound",
+	}
+	if line := <-raw; !reflect.DeepEqual(line, expected) {
+		t.Errorf("Expected %#v, but got %#v", expected, line)
+	}
+	if line["$level"] != "lol" {
+		t.Error("Expected the caller's line not to be modified")
+	}
+}
+
+func TestRegisterSystemField(t *testing.T) {
+	t.Parallel()
+	if !reserved("$test_host") || !reserved("$level") {
+		t.Error("Expected registered system fields to be reserved")
+	}
+	if reserved("$other") || reserved("level") {
+		t.Error("Expected unregistered fields not to be reserved")
+	}
+	expectPanic(t, "registering a key without $", func() {
+		RegisterSystemField("host")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"sync"
 )
 
 const bufferSize = 100
@@ -23,8 +24,31 @@ func init() {
 	Stdout = ch
 }
 
-func stdout(line map[string]interface{}) {
-	Stdout <- Format(line)
+// A target is a destination for log lines. Targets can be canceled, after which
+// they will never again be sent a line.
+type target struct {
+	lock     sync.RWMutex
+	canceled bool
+	fn       func(*record)
+}
+
+func (t *target) send(r *record) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if !t.canceled {
+		t.fn(r)
+	}
+}
+
+// Cancel the target. This blocks until all in-flight sends have completed.
+func (t *target) cancel() {
+	t.lock.Lock()
+	t.canceled = true
+	t.lock.Unlock()
+}
+
+func stdout(r *record) {
+	Stdout <- r.format()
 }
 
 func stdoutWriter(ch <-chan string) {
@@ -1,15 +1,78 @@
 package slog
 
 type targetCache struct {
-	targets       map[Level]func(map[string]interface{})
-	defaultTarget func(map[string]interface{})
-	parent        *targetCache
+	targets       map[Level]*target
+	thresholds    thresholds
+	routes        []route
+	defaultTarget *target
+	// Run on every line before it is dispatched, parents' hooks first.
+	hooks []*hook
+	// Lines sent to these targets are subsequently dispatched to the
+	// corresponding cache as well. See Logger.SetForward.
+	forwards map[*target]*targetCache
+	parent   *targetCache
+	logger   *logger
 }
 
-func (tc targetCache) dispatch(level Level, line map[string]interface{}) {
-	if t, ok := tc.targets[level]; ok {
-		t(line)
-	} else {
-		tc.defaultTarget(line)
+// Lines are routed to the target of the first route whose predicate matches,
+// then to the target registered for their exact level if there is one, then to
+// the target with the highest threshold at or below their level, and finally to
+// the default target.
+func (tc *targetCache) dispatch(r *record) {
+	t := tc.match(r)
+	if t == nil {
+		return
 	}
+	next, ok := tc.forwards[t]
+	if ok {
+		// Every target after this one reads the record too, so they
+		// can't be handed the same map.
+		r.shared = true
+	}
+	t.send(r)
+	if ok {
+		next.dispatch(r)
+	}
+}
+
+func (tc *targetCache) match(r *record) *target {
+	for _, rt := range tc.routes {
+		if rt.pred(Line{r}) {
+			return rt.target
+		}
+	}
+	if t, ok := tc.targets[r.level]; ok {
+		return t
+	}
+	for _, th := range tc.thresholds {
+		if th.level <= r.level {
+			return th.target
+		}
+	}
+	return tc.defaultTarget
+}
+
+type threshold struct {
+	level  Level
+	target *target
+}
+
+type thresholds []threshold
+
+func (t thresholds) Len() int {
+	return len(t)
+}
+
+func (t thresholds) Less(i, j int) bool {
+	// We sort largest to smallest
+	return t[i].level > t[j].level
+}
+
+func (t thresholds) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
+
+type route struct {
+	pred   Predicate
+	target *target
 }
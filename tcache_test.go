@@ -4,29 +4,75 @@ import "testing"
 
 func TestTCache(t *testing.T) {
 	tc := targetCache{
-		targets: make(map[Level]func(map[string]interface{})),
+		targets: make(map[Level]*target),
 	}
 	ch := make(chan string, 1)
-	tc.targets[LDebug] = func(_ map[string]interface{}) {
+	tc.targets[LDebug] = &target{fn: func(_ *record) {
 		ch <- "debug"
-	}
-	tc.targets[LError] = func(_ map[string]interface{}) {
+	}}
+	tc.targets[LError] = &target{fn: func(_ *record) {
 		ch <- "error"
-	}
-	tc.defaultTarget = func(_ map[string]interface{}) {
+	}}
+	tc.defaultTarget = &target{fn: func(_ *record) {
 		ch <- "idk"
-	}
+	}}
 
-	tc.dispatch(LDebug, nil)
+	tc.dispatch(&record{level: LDebug})
 	if out := <-ch; out != "debug" {
 		t.Errorf("expected debug, got %s", out)
 	}
-	tc.dispatch(LError, nil)
+	tc.dispatch(&record{level: LError})
 	if out := <-ch; out != "error" {
 		t.Errorf("expected error, got %s", out)
 	}
-	tc.dispatch(LWarn, nil)
+	tc.dispatch(&record{level: LWarn})
 	if out := <-ch; out != "idk" {
 		t.Errorf("expected idk, got %s", out)
 	}
+
+	tc.defaultTarget.cancel()
+	tc.dispatch(&record{level: LWarn})
+	select {
+	case out := <-ch:
+		t.Errorf("expected nothing from canceled target, got %s", out)
+	default:
+	}
+}
+
+func TestTCacheThresholds(t *testing.T) {
+	tc := targetCache{
+		targets: make(map[Level]*target),
+	}
+	ch := make(chan string, 1)
+	tc.targets[LError] = &target{fn: func(_ *record) {
+		ch <- "error"
+	}}
+	tc.thresholds = thresholds{
+		{LWarn, &target{fn: func(_ *record) {
+			ch <- "warn+"
+		}}},
+		{LInfo, &target{fn: func(_ *record) {
+			ch <- "info+"
+		}}},
+	}
+	tc.defaultTarget = &target{fn: func(_ *record) {
+		ch <- "idk"
+	}}
+
+	for _, test := range []struct {
+		level Level
+		out   string
+	}{
+		{LDebug, "idk"},
+		{LInfo, "info+"},
+		{LWarn, "warn+"},
+		{LError, "error"},
+		{LError + 1, "warn+"},
+	} {
+		tc.dispatch(&record{level: test.level})
+		if out := <-ch; out != test.out {
+			t.Errorf("%v: expected %s, got %s", test.level, test.out,
+				out)
+		}
+	}
 }
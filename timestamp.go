@@ -0,0 +1,106 @@
+package slog
+
+import "time"
+
+// Precision is the number of fractional digits with which timestamps are
+// formatted.
+type Precision int
+
+const (
+	Seconds Precision = 0
+	Millis  Precision = 3
+	Micros  Precision = 6
+	Nanos   Precision = 9
+)
+
+// TimeFormat describes how timestamps are formatted.
+type TimeFormat struct {
+	// The number of fractional digits to print. Times are truncated, not
+	// rounded, to this precision.
+	Precision Precision
+	// Format times in the local time zone instead of UTC.
+	Local bool
+}
+
+// DefaultTimeFormat is the format used for "$time" and all other time.Time
+// values by Format.
+var DefaultTimeFormat = TimeFormat{Precision: Nanos}
+
+// Clock returns the current time. It is used to timestamp log lines, and may be
+// replaced (for instance, in tests) in order to make timestamps deterministic.
+// Like Stdout, it is not safe to replace while anyone might be logging.
+var Clock = time.Now
+
+// AppendFormat appends the formatted form of t to dst. Times are formatted as in
+// RFC 3339, with exactly as many fractional digits as f's Precision calls for,
+// which makes them fixed-width: UTC times end with a "Z", and local times with
+// a numeric zone offset. Years before 0 or after 9999 can't be formatted this
+// way, so they fall back to time.RFC3339Nano.
+func (f TimeFormat) AppendFormat(dst []byte, t time.Time) []byte {
+	if f.Local {
+		return f.appendIn(dst, t.Local())
+	}
+	return f.appendIn(dst, t.UTC())
+}
+
+// Append t in whatever location it's in. The caller is responsible for making
+// sure it's in UTC unless f is Local.
+func (f TimeFormat) appendIn(dst []byte, t time.Time) []byte {
+	year, month, day := t.Date()
+	if year < 0 || year > 9999 {
+		return t.AppendFormat(dst, time.RFC3339Nano)
+	}
+	hour, min, sec := t.Clock()
+
+	dst = appendDigits(dst, year, 4)
+	dst = append(dst, '-')
+	dst = appendDigits(dst, int(month), 2)
+	dst = append(dst, '-')
+	dst = appendDigits(dst, day, 2)
+	dst = append(dst, 'T')
+	dst = appendDigits(dst, hour, 2)
+	dst = append(dst, ':')
+	dst = appendDigits(dst, min, 2)
+	dst = append(dst, ':')
+	dst = appendDigits(dst, sec, 2)
+
+	if p := int(f.Precision); p > 0 {
+		if p > 9 {
+			p = 9
+		}
+		frac := t.Nanosecond()
+		for i := p; i < 9; i++ {
+			frac /= 10
+		}
+		dst = append(dst, '.')
+		dst = appendDigits(dst, frac, p)
+	}
+
+	if !f.Local {
+		return append(dst, 'Z')
+	}
+	_, offset := t.Zone()
+	offset /= 60
+	if offset < 0 {
+		dst = append(dst, '-')
+		offset = -offset
+	} else {
+		dst = append(dst, '+')
+	}
+	dst = appendDigits(dst, offset/60, 2)
+	dst = append(dst, ':')
+	return appendDigits(dst, offset%60, 2)
+}
+
+// Append n to dst as exactly width decimal digits, zero-padded. n must be
+// non-negative and fit in width.
+func appendDigits(dst []byte, n, width int) []byte {
+	for i := 0; i < width; i++ {
+		dst = append(dst, '0')
+	}
+	for i := len(dst) - 1; n > 0; i-- {
+		dst[i] = byte('0' + n%10)
+		n /= 10
+	}
+	return dst
+}
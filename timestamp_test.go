@@ -0,0 +1,92 @@
+package slog
+
+import (
+	"testing"
+	"time"
+)
+
+var plusFive = time.FixedZone("PLUS5", 5*60*60+30*60)
+var minusThree = time.FixedZone("MINUS3", -3*60*60)
+
+var timestampTests = []struct {
+	t   time.Time
+	f   TimeFormat
+	out string
+}{
+	{
+		time.Date(2014, 6, 3, 12, 4, 5, 6007008, time.UTC),
+		TimeFormat{Precision: Nanos},
+		"2014-06-03T12:04:05.006007008Z",
+	},
+	{
+		time.Date(2014, 6, 3, 12, 4, 5, 6007008, time.UTC),
+		TimeFormat{Precision: Micros},
+		"2014-06-03T12:04:05.006007Z",
+	},
+	{
+		time.Date(2014, 6, 3, 12, 4, 5, 6997008, time.UTC),
+		TimeFormat{Precision: Millis},
+		"2014-06-03T12:04:05.006Z",
+	},
+	{
+		time.Date(2014, 6, 3, 12, 4, 5, 999999999, time.UTC),
+		TimeFormat{Precision: Seconds},
+		"2014-06-03T12:04:05Z",
+	},
+	{
+		time.Date(2014, 6, 3, 12, 4, 5, 0, time.UTC),
+		TimeFormat{Precision: Nanos},
+		"2014-06-03T12:04:05.000000000Z",
+	},
+	{
+		time.Date(14, 1, 1, 0, 0, 0, 0, plusFive),
+		TimeFormat{Precision: Millis},
+		"0013-12-31T18:30:00.000Z",
+	},
+	{
+		time.Date(2014, 6, 3, 12, 4, 5, 6007008, plusFive),
+		TimeFormat{Precision: Millis, Local: true},
+		"2014-06-03T12:04:05.006+05:30",
+	},
+	{
+		time.Date(2014, 6, 3, 12, 4, 5, 6007008, minusThree),
+		TimeFormat{Precision: Millis, Local: true},
+		"2014-06-03T12:04:05.006-03:00",
+	},
+	{
+		time.Date(2014, 6, 3, 12, 4, 5, 6007008, time.UTC),
+		TimeFormat{Precision: Millis, Local: true},
+		"2014-06-03T12:04:05.006+00:00",
+	},
+	{
+		time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC),
+		TimeFormat{Precision: Millis},
+		"10000-01-01T00:00:00Z",
+	},
+}
+
+func TestTimestamp(t *testing.T) {
+	t.Parallel()
+	for _, test := range timestampTests {
+		var out string
+		if test.f.Local {
+			// Let's not depend on the machine's time zone.
+			out = string(test.f.appendIn(nil, test.t))
+		} else {
+			out = string(test.f.AppendFormat(nil, test.t))
+		}
+		if out != test.out {
+			t.Errorf("Expected %v formatted with %+v to be %q, got %q",
+				test.t, test.f, test.out, out)
+		}
+	}
+}
+
+func BenchmarkTimestamp(b *testing.B) {
+	buf := make([]byte, 0, 64)
+	now := time.Now()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = DefaultTimeFormat.AppendFormat(buf[:0], now)
+	}
+}